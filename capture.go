@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/OmegaRelay/mqtt-tui/connection"
+	"github.com/OmegaRelay/mqtt-tui/form"
+	"github.com/OmegaRelay/mqtt-tui/program"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/google/uuid"
+)
+
+type captureInputs struct {
+	Name textinput.Model // empty uses the file name
+	File textinput.Model
+}
+
+// captureModel adds or edits a connection which shows a capture file
+// instead of connecting to a broker
+type captureModel struct {
+	id   string // of the edited connection, empty for a new one
+	form form.Model
+}
+
+func NewCaptureModel(conn *connection.Model) captureModel {
+	inputs := &captureInputs{
+		Name: textinput.New(),
+		File: textinput.New(),
+	}
+	inputs.File.Placeholder = "session recorded with R or NDJSON export"
+
+	m := captureModel{form: form.New("Open Capture", nil)}
+	if conn != nil {
+		m.id = conn.Data().Id
+		inputs.Name.SetValue(conn.Data().Name)
+		inputs.File.SetValue(conn.Data().Capture)
+	}
+	m.form.SetInputs(inputs)
+	return m
+}
+
+func (m captureModel) Init() tea.Cmd {
+	return m.form.Init()
+}
+
+func (m captureModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case form.SubmitMsg:
+		if msg.Id == m.form.Id() {
+			return nil, m.complete
+		}
+	case form.CancelMsg:
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.Update(msg)
+	return m, cmd
+}
+
+func (m captureModel) View() string {
+	content := m.form.View()
+	width, height, _ := term.GetSize(0)
+	widget := viewport.New(width-4, min(lipgloss.Height(content), height-4))
+	widget.SetContent(content)
+	return styles.FocusedBorderStyle.Render(widget.View())
+}
+
+func (m captureModel) complete() tea.Msg {
+	inputs := m.form.Inputs().(*captureInputs)
+	file := strings.TrimSpace(inputs.File.Value())
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(file, "~/") {
+		file = path.Join(home, file[2:])
+	}
+	if file == "" {
+		return program.ErrorMsg{Err: fmt.Errorf("no capture file to open")}
+	}
+	// the connection outlives the working directory it was opened from
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if _, err := os.Stat(file); err != nil {
+		return program.ErrorMsg{Err: err}
+	}
+
+	name := strings.TrimSpace(inputs.Name.Value())
+	if name == "" {
+		name = path.Base(file)
+	}
+	id := m.id
+	if id == "" {
+		id = uuid.NewString()
+	}
+	return newConnectionMsg(connection.NewModel(connection.Data{
+		Id:      id,
+		Name:    name,
+		Capture: file,
+	}))
+}
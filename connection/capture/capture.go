@@ -0,0 +1,129 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync/atomic"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+)
+
+// Client is a client.Client without a broker for connections which show a
+// capture file, it connects at once and nothing can be published
+type Client struct {
+	opts      client.Options
+	connected atomic.Bool
+}
+
+func New(opts client.Options) *Client {
+	return &Client{opts: opts}
+}
+
+func (c *Client) Connect() *client.Token {
+	c.connected.Store(true)
+	if c.opts.OnConnect != nil {
+		c.opts.OnConnect()
+	}
+	return client.NewCompletedToken(0, nil)
+}
+
+func (c *Client) Disconnect(quiesce uint) {
+	c.connected.Store(false)
+}
+
+func (c *Client) IsConnected() bool {
+	return c.connected.Load()
+}
+
+// Subscribe succeeds for any filter, the connection hands the captured
+// messages to its subscriptions itself
+func (c *Client) Subscribe(topic string, qos byte) *client.Token {
+	return client.NewCompletedToken(qos, nil)
+}
+
+func (c *Client) Unsubscribe(topics ...string) *client.Token {
+	return client.NewCompletedToken(0, nil)
+}
+
+func (c *Client) Publish(msg client.Message) *client.Token {
+	return client.NewCompletedToken(0, errors.New("cannot publish on a capture file"))
+}
+
+// line is a line of a session recording or of an NDJSON export, whose
+// payloads are either base64 or, with the utf8 encoding, plain text
+type line struct {
+	msglog.Entry
+	Payload  string `json:"payload"`
+	Encoding string `json:"encoding"`
+}
+
+// Load reads the messages of a session recorded with R or of an NDJSON
+// export, oldest first
+func Load(path string) ([]msglog.Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open capture: %w", err)
+	}
+	defer file.Close()
+
+	entries := make([]msglog.Entry, 0)
+	reader := bufio.NewReader(file)
+	for n := 1; ; n++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			e, parseErr := parseLine(data)
+			switch {
+			case parseErr == nil:
+				entries = append(entries, e)
+			case err == io.EOF:
+				// the last line of a recording cut short
+			default:
+				return nil, fmt.Errorf("%s:%d: %w", path, n, parseErr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read capture: %w", err)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a msglog.Entry, b msglog.Entry) int {
+		return a.At.Compare(b.At)
+	})
+	return entries, nil
+}
+
+func parseLine(data []byte) (msglog.Entry, error) {
+	var l line
+	err := json.Unmarshal(data, &l)
+	if err != nil {
+		return msglog.Entry{}, fmt.Errorf("not a captured message: %w", err)
+	}
+	if l.Topic == "" || l.At.IsZero() {
+		return msglog.Entry{}, errors.New("not a captured message: missing topic or time")
+	}
+
+	e := l.Entry
+	switch l.Encoding {
+	case "utf8":
+		e.Payload = []byte(l.Payload)
+	case "", "base64":
+		e.Payload, err = base64.StdEncoding.DecodeString(l.Payload)
+		if err != nil {
+			return msglog.Entry{}, fmt.Errorf("invalid payload: %w", err)
+		}
+	default:
+		return msglog.Entry{}, fmt.Errorf("unknown payload encoding %q", l.Encoding)
+	}
+	return e, nil
+}
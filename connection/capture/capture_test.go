@@ -0,0 +1,117 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/export"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // topic and payload of the entries, oldest first
+		err     string   // part of the error, empty if it loads
+	}{
+		{"recording", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":"aGk=","qos":1}
+{"at":"2025-08-10T12:00:01Z","topic":"b","payload":"AP8="}
+`, []string{"a hi", "b \x00\xff"}, ""},
+		{"export", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":"{\"t\":1}","encoding":"utf8","size":7}
+{"at":"2025-08-10T12:00:01Z","topic":"b","payload":"AP8=","encoding":"base64","size":2}
+`, []string{`a {"t":1}`, "b \x00\xff"}, ""},
+		{"sorted by time", `{"at":"2025-08-10T12:00:01Z","topic":"b","payload":""}
+{"at":"2025-08-10T12:00:00Z","topic":"a","payload":""}
+`, []string{"a ", "b "}, ""},
+		{"empty lines", "\n" + `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":""}` + "\n\n", []string{"a "}, ""},
+		{"last line cut short", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":""}
+{"at":"2025-08-10T12:00:01Z","top`, []string{"a "}, ""},
+		{"broken line", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":""}
+{"at":
+{"at":"2025-08-10T12:00:01Z","topic":"b","payload":""}
+`, nil, "capture.ndjson:2: not a captured message"},
+		{"missing topic", `{"at":"2025-08-10T12:00:00Z","payload":""}
+`, nil, "missing topic or time"},
+		{"unknown encoding", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":"","encoding":"hex"}
+`, nil, `unknown payload encoding "hex"`},
+		{"invalid base64", `{"at":"2025-08-10T12:00:00Z","topic":"a","payload":"!"}
+`, nil, "invalid payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.ndjson")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Load(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(entries))
+			for i, e := range entries {
+				got[i] = e.Topic + " " + string(e.Payload)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("loaded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadRoundTrip loads what the recorder and the NDJSON export write
+func TestLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2025, 8, 10, 12, 0, 0, 0, time.UTC)
+	received := []client.Message{
+		{Topic: "sensors/1/temp", Payload: []byte(`{"temp":21.5}`), Qos: 1},
+		{Topic: "bin/data", Payload: []byte{0x00, 0xff}, Retained: true},
+	}
+
+	recording := filepath.Join(dir, "session.ndjson")
+	recorder := msglog.New(recording, -1)
+	if err := recorder.Open(); err != nil {
+		t.Fatal(err)
+	}
+	sub := subscription.NewModel(subscription.Data{})
+	for i, msg := range received {
+		recorder.Append(msglog.NewEntry(msg, at.Add(time.Duration(i)*time.Second)))
+		sub.Restore(msg, at.Add(time.Duration(i)*time.Second), subscription.Observation{})
+	}
+	recorder.Close()
+
+	exported := filepath.Join(dir, "export.ndjson")
+	messages := sub.Messages()
+	if err := export.Write(exported, export.FormatNdjson, []subscription.Message{messages[1], messages[0]}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{recording, exported} {
+		entries, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(received) {
+			t.Fatalf("%s: loaded %d entries, want %d", filepath.Base(path), len(entries), len(received))
+		}
+		for i, e := range entries {
+			msg := e.Message()
+			if msg.Topic != received[i].Topic || string(msg.Payload) != string(received[i].Payload) ||
+				msg.Qos != received[i].Qos || msg.Retained != received[i].Retained || !e.At.Equal(at.Add(time.Duration(i)*time.Second)) {
+				t.Errorf("%s: entry %d is %+v, want %+v", filepath.Base(path), i, e, received[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package chart
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	lineStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("38"))
+	axisStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+)
+
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+type Stats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Avg   float64
+	Last  float64 // value of the newest point
+}
+
+// Summarize returns the statistics of points, which do not need to be sorted
+func Summarize(points []Point) Stats {
+	if len(points) == 0 {
+		return Stats{}
+	}
+	s := Stats{Count: len(points), Min: math.Inf(1), Max: math.Inf(-1)}
+	sum := 0.0
+	var last time.Time
+	for _, p := range points {
+		s.Min = min(s.Min, p.Value)
+		s.Max = max(s.Max, p.Value)
+		sum += p.Value
+		if !p.At.Before(last) {
+			last = p.At
+			s.Last = p.Value
+		}
+	}
+	s.Avg = sum / float64(len(points))
+	return s
+}
+
+// FormatValue formats a value for the axis and statistics
+func FormatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// braille dot bits by column and row of a 2x4 cell
+var dots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// Render draws points between from and to as a braille line chart of width
+// and height characters, including the value and time axes
+func Render(points []Point, from time.Time, to time.Time, width int, height int) string {
+	points = slices.Clone(points)
+	slices.SortFunc(points, func(a, b Point) int { return a.At.Compare(b.At) })
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		lo, hi = min(lo, p.Value), max(hi, p.Value)
+	}
+	if len(points) == 0 {
+		lo, hi = 0, 1
+	}
+	if lo == hi {
+		// a flat line is drawn in the middle
+		pad := max(math.Abs(lo)*0.1, 1)
+		lo, hi = lo-pad, hi+pad
+	}
+
+	hiLabel, loLabel := FormatValue(hi), FormatValue(lo)
+	labelWidth := max(len(hiLabel), len(loLabel))
+	plotWidth := max(width-labelWidth-2, 1)
+	plotHeight := max(height-2, 1) // the time axis takes two lines
+
+	cells := make([][]rune, plotHeight)
+	for i := range cells {
+		cells[i] = make([]rune, plotWidth)
+	}
+	pixelWidth, pixelHeight := plotWidth*2, plotHeight*4
+	span := to.Sub(from)
+	pixel := func(p Point) (int, int) {
+		x := 0
+		if span > 0 {
+			x = int(float64(p.At.Sub(from)) / float64(span) * float64(pixelWidth-1))
+		}
+		y := int(math.Round((hi - p.Value) / (hi - lo) * float64(pixelHeight-1)))
+		return min(max(x, 0), pixelWidth-1), min(max(y, 0), pixelHeight-1)
+	}
+	set := func(x int, y int) {
+		cells[y/4][x/2] |= dots[x%2][y%4]
+	}
+
+	for i, p := range points {
+		x, y := pixel(p)
+		if i == 0 {
+			set(x, y)
+			continue
+		}
+		px, py := pixel(points[i-1])
+		line(px, py, x, y, set)
+	}
+
+	b := strings.Builder{}
+	for row, runes := range cells {
+		label := ""
+		switch row {
+		case 0:
+			label = hiLabel
+		case plotHeight - 1:
+			label = loLabel
+		}
+		tick := " │"
+		if label != "" {
+			tick = " ┤"
+		}
+		b.WriteString(axisStyle.Render(strings.Repeat(" ", labelWidth-len(label)) + label + tick))
+		plot := make([]rune, len(runes))
+		for i, r := range runes {
+			if r == 0 {
+				plot[i] = ' '
+			} else {
+				plot[i] = 0x2800 + r
+			}
+		}
+		b.WriteString(lineStyle.Render(string(plot)))
+		b.WriteString("\n")
+	}
+
+	start, end := from.Format("15:04:05"), to.Format("15:04:05")
+	b.WriteString(axisStyle.Render(strings.Repeat(" ", labelWidth+1) + "└" + strings.Repeat("─", plotWidth)))
+	b.WriteString("\n")
+	gap := max(plotWidth-len(start)-len(end), 1)
+	b.WriteString(axisStyle.Render(strings.Repeat(" ", labelWidth+2) + start + strings.Repeat(" ", gap) + end))
+	return b.String()
+}
+
+// line calls set for every pixel from (x0, y0) to (x1, y1)
+func line(x0 int, y0 int, x1 int, y1 int, set func(int, int)) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	err := dx + dy
+	for {
+		set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
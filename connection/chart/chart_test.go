@@ -0,0 +1,99 @@
+package chart
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+func points(values ...float64) []Point {
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{At: start.Add(time.Duration(i) * time.Second), Value: v}
+	}
+	return points
+}
+
+func TestSummarize(t *testing.T) {
+	unsorted := points(3, 1, 2)
+	unsorted[0], unsorted[2] = unsorted[2], unsorted[0]
+
+	tests := []struct {
+		name   string
+		points []Point
+		want   Stats
+	}{
+		{"empty", nil, Stats{}},
+		{"single point", points(-2.5), Stats{Count: 1, Min: -2.5, Max: -2.5, Avg: -2.5, Last: -2.5}},
+		{"constant", points(4, 4, 4), Stats{Count: 3, Min: 4, Max: 4, Avg: 4, Last: 4}},
+		{"several", points(1, 5, 3), Stats{Count: 3, Min: 1, Max: 5, Avg: 3, Last: 3}},
+		{"last is the newest", unsorted, Stats{Count: 3, Min: 1, Max: 3, Avg: 2, Last: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.points); got != tt.want {
+				t.Errorf("Summarize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// plotted returns the value labels and the braille cells drawn in each row
+func plotted(chart string, height int) (labels []string, rows []string) {
+	lines := strings.Split(chart, "\n")
+	for _, line := range lines[:height-2] {
+		i := strings.IndexAny(line, "┤│")
+		if label := strings.TrimSpace(line[:i]); label != "" {
+			labels = append(labels, label)
+		}
+		rows = append(rows, strings.TrimSpace(line[i+len("┤"):]))
+	}
+	return labels, rows
+}
+
+func TestRender(t *testing.T) {
+	const width, height = 30, 8
+
+	tests := []struct {
+		name      string
+		points    []Point
+		labels    []string // highest and lowest value on the axis
+		drawnRows []int    // rows with something drawn in them
+	}{
+		{"empty", nil, []string{"1", "0"}, nil},
+		{"single point", points(5)[:1], []string{"6", "4"}, []int{3}},
+		{"constant", points(20, 20, 20, 20), []string{"22", "18"}, []int{3}},
+		{"zero", points(0, 0), []string{"1", "-1"}, []int{3}},
+		{"rising", points(0, 10), []string{"10", "0"}, []int{0, 1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart := Render(tt.points, start, start.Add(3*time.Second), width, height)
+			if n := strings.Count(chart, "\n") + 1; n != height {
+				t.Fatalf("%d lines, want %d:\n%s", n, height, chart)
+			}
+			if !strings.Contains(chart, "12:00:00") || !strings.Contains(chart, "12:00:03") {
+				t.Errorf("time axis is missing:\n%s", chart)
+			}
+
+			labels, rows := plotted(chart, height)
+			if strings.Join(labels, " ") != strings.Join(tt.labels, " ") {
+				t.Errorf("labels %q, want %q", labels, tt.labels)
+			}
+			var drawn []int
+			for i, row := range rows {
+				if row != "" {
+					drawn = append(drawn, i)
+				}
+			}
+			if !slices.Equal(drawn, tt.drawnRows) {
+				t.Errorf("drawn rows %v, want %v:\n%s", drawn, tt.drawnRows, chart)
+			}
+		})
+	}
+}
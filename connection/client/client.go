@@ -0,0 +1,128 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+const (
+	ProtocolVersion311 = "3.1.1"
+	ProtocolVersion5   = "5.0"
+)
+
+var protocolVersionChoices = []string{
+	ProtocolVersion311,
+	ProtocolVersion5,
+}
+
+type Message struct {
+	Topic     string
+	Payload   []byte
+	Qos       byte
+	Retained  bool
+	Duplicate bool
+	MessageId uint16
+
+	// Filter is the subscription the broker sent the message for, only known
+	// for MQTT v5 brokers which support subscription identifiers
+	Filter string
+
+	// Properties is only set for messages received over MQTT v5
+	Properties *Properties
+}
+
+type Properties struct {
+	ContentType            string
+	ResponseTopic          string
+	CorrelationData        []byte
+	PayloadFormat          *byte
+	MessageExpiry          *uint32
+	TopicAlias             *uint16
+	SubscriptionIdentifier *int
+	User                   []UserProperty
+}
+
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+type MessageHandler func(msg Message)
+
+type Options struct {
+	ProtocolVersion string
+	BrokerUrl       string
+	ClientId        string
+	Username        string
+	Password        string
+	TlsConfig       *tls.Config
+	Headers         http.Header // sent with the WebSocket upgrade request
+
+	// OnMessage receives every message from the broker once, whichever
+	// subscriptions it matches
+	OnMessage        MessageHandler
+	OnConnect        func()
+	OnConnectionLost func(err error)
+	OnReconnecting   func()
+	OnConnectAttempt func()
+	// OnConnectError is called when an attempt to connect fails, the client
+	// keeps trying
+	OnConnectError func(err error)
+}
+
+// Client is the subset of an MQTT client used by the connection views, it
+// hides whether the underlying paho client speaks MQTT v3.1.1 or v5.
+type Client interface {
+	Connect() *Token
+	Disconnect(quiesce uint)
+	IsConnected() bool
+	Subscribe(topic string, qos byte) *Token
+	Unsubscribe(topics ...string) *Token
+	Publish(msg Message) *Token
+}
+
+func New(opts Options) (Client, error) {
+	switch opts.ProtocolVersion {
+	case "", ProtocolVersion311:
+		return newV3(opts), nil
+	case ProtocolVersion5:
+		return newV5(opts)
+	default:
+		return nil, fmt.Errorf("unsupported protocol version %q", opts.ProtocolVersion)
+	}
+}
+
+func ProtocolVersionChoices() []string {
+	return protocolVersionChoices
+}
+
+// Token is the result of an asynchronous client operation. ReasonCode is
+// only meaningful once Done has been closed.
+type Token struct {
+	done       chan struct{}
+	err        error
+	reasonCode byte
+}
+
+func newToken() *Token {
+	return &Token{done: make(chan struct{})}
+}
+
+func (t *Token) complete(reasonCode byte, err error) {
+	t.reasonCode = reasonCode
+	t.err = err
+	close(t.done)
+}
+
+// NewCompletedToken returns a token which is already done, for clients whose
+// operations complete immediately
+func NewCompletedToken(reasonCode byte, err error) *Token {
+	t := newToken()
+	t.complete(reasonCode, err)
+	return t
+}
+
+func (t *Token) Done() <-chan struct{} { return t.done }
+func (t *Token) Error() error          { return t.err }
+func (t *Token) ReasonCode() byte      { return t.reasonCode }
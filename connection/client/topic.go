@@ -0,0 +1,36 @@
+package client
+
+import "strings"
+
+// MatchTopic reports whether a topic name matches a subscription filter using
+// the MQTT wildcard rules ('+' matches one level, '#' the remaining levels).
+// Shared subscription filters ($share/<group>/<filter>) match on <filter>.
+func MatchTopic(filter string, topic string) bool {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+
+	// topics starting with '$' are not matched by filters starting with a wildcard
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return i == len(filterLevels)-1
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
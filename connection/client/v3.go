@@ -0,0 +1,118 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+type v3Client struct {
+	client mqtt.Client
+}
+
+func newV3(o Options) *v3Client {
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(o.BrokerUrl)
+	opts.SetClientID(o.ClientId)
+	if o.Username != "" {
+		opts.SetUsername(o.Username)
+	}
+	if o.Password != "" {
+		opts.SetPassword(o.Password)
+	}
+	if o.TlsConfig != nil {
+		opts.SetTLSConfig(o.TlsConfig)
+	}
+	if o.Headers != nil {
+		opts.SetHTTPHeaders(o.Headers)
+	}
+
+	// subscriptions have no handlers of their own so every message goes to
+	// the default handler, once for every copy the broker sends
+	opts.SetDefaultPublishHandler(func(_ mqtt.Client, msg mqtt.Message) {
+		if o.OnMessage == nil {
+			return
+		}
+		o.OnMessage(Message{
+			Topic:     msg.Topic(),
+			Payload:   msg.Payload(),
+			Qos:       msg.Qos(),
+			Retained:  msg.Retained(),
+			Duplicate: msg.Duplicate(),
+			MessageId: msg.MessageID(),
+		})
+	})
+	opts.OnConnect = func(mqtt.Client) {
+		if o.OnConnect != nil {
+			o.OnConnect()
+		}
+	}
+	opts.OnConnectionLost = func(_ mqtt.Client, err error) {
+		if o.OnConnectionLost != nil {
+			o.OnConnectionLost(err)
+		}
+	}
+	opts.OnReconnecting = func(mqtt.Client, *mqtt.ClientOptions) {
+		if o.OnReconnecting != nil {
+			o.OnReconnecting()
+		}
+	}
+	opts.OnConnectAttempt = func(_ *url.URL, tlsCfg *tls.Config) *tls.Config {
+		if o.OnConnectAttempt != nil {
+			o.OnConnectAttempt()
+		}
+		return tlsCfg
+	}
+	opts.ConnectRetry = true
+	opts.AutoReconnect = true
+
+	return &v3Client{client: mqtt.NewClient(opts)}
+}
+
+func (c *v3Client) Connect() *Token {
+	return wrapV3Token(c.client.Connect(), nil)
+}
+
+func (c *v3Client) Disconnect(quiesce uint) {
+	c.client.Disconnect(quiesce)
+}
+
+func (c *v3Client) IsConnected() bool {
+	return c.client.IsConnected()
+}
+
+func (c *v3Client) Subscribe(topic string, qos byte) *Token {
+	token := c.client.Subscribe(topic, qos, nil)
+	return wrapV3Token(token, func() (byte, error) {
+		code, ok := token.(*mqtt.SubscribeToken).Result()[topic]
+		if ok && code == 0x80 {
+			return code, fmt.Errorf("subscribe to %s was rejected by the broker", topic)
+		}
+		return code, nil
+	})
+}
+
+func (c *v3Client) Unsubscribe(topics ...string) *Token {
+	return wrapV3Token(c.client.Unsubscribe(topics...), nil)
+}
+
+func (c *v3Client) Publish(msg Message) *Token {
+	return wrapV3Token(c.client.Publish(msg.Topic, msg.Qos, msg.Retained, msg.Payload), nil)
+}
+
+// wrapV3Token completes a Token once the paho token is done, result is used
+// to read a return code from the completed paho token if there is one.
+func wrapV3Token(token mqtt.Token, result func() (byte, error)) *Token {
+	t := newToken()
+	go func() {
+		<-token.Done()
+		if err := token.Error(); err != nil || result == nil {
+			t.complete(0, err)
+			return
+		}
+		t.complete(result())
+	}()
+	return t
+}
@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const kV5KeepAlive = 30
+const kV5TopicAliasMaximum = 64
+const kV5RequestTimeout = 10 * time.Second
+
+type v5Client struct {
+	opts Options
+	cfg  autopaho.ClientConfig
+
+	cm      *autopaho.ConnectionManager
+	cancel  context.CancelFunc
+	isUp    atomic.Bool
+	everUp  atomic.Bool
+	mu      sync.Mutex
+	aliases map[uint16]string
+
+	// every filter gets its own subscription identifier so the copies of a
+	// message sent for overlapping subscriptions can be told apart
+	subIds          map[string]int
+	filters         map[int]string
+	subIdsAvailable atomic.Bool
+}
+
+func newV5(o Options) (*v5Client, error) {
+	brokerUrl, err := url.Parse(o.BrokerUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker url: %w", err)
+	}
+
+	c := &v5Client{
+		opts:    o,
+		aliases: make(map[uint16]string),
+		subIds:  make(map[string]int),
+		filters: make(map[int]string),
+	}
+	// brokers support them unless the CONNACK says otherwise
+	c.subIdsAvailable.Store(true)
+	c.cfg = autopaho.ClientConfig{
+		ServerUrls:      []*url.URL{brokerUrl},
+		TlsCfg:          o.TlsConfig,
+		KeepAlive:       kV5KeepAlive,
+		ConnectUsername: o.Username,
+		ConnectPassword: []byte(o.Password),
+		ConnectPacketBuilder: func(cp *paho.Connect, _ *url.URL) (*paho.Connect, error) {
+			c.onConnectAttempt()
+			if cp.Properties == nil {
+				cp.Properties = &paho.ConnectProperties{}
+			}
+			topicAliasMaximum := uint16(kV5TopicAliasMaximum)
+			cp.Properties.TopicAliasMaximum = &topicAliasMaximum
+			return cp, nil
+		},
+		OnConnectionUp: func(_ *autopaho.ConnectionManager, connack *paho.Connack) {
+			c.subIdsAvailable.Store(connack.Properties == nil || connack.Properties.SubIDAvailable)
+			c.isUp.Store(true)
+			c.everUp.Store(true)
+			if o.OnConnect != nil {
+				o.OnConnect()
+			}
+		},
+		OnConnectError: func(err error) {
+			if o.OnConnectError != nil {
+				o.OnConnectError(err)
+			}
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:          o.ClientId,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){c.onPublishReceived},
+			OnClientError:     c.onConnectionLost,
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				err := fmt.Errorf("disconnected by server: %s", ReasonString(d.ReasonCode))
+				if d.Properties != nil && d.Properties.ReasonString != "" {
+					err = fmt.Errorf("%w (%s)", err, d.Properties.ReasonString)
+				}
+				c.onConnectionLost(err)
+			},
+		},
+	}
+	if o.Headers != nil {
+		c.cfg.WebSocketCfg = &autopaho.WebSocketConfig{
+			Header: func(*url.URL, *tls.Config) http.Header { return o.Headers },
+		}
+	}
+	return c, nil
+}
+
+func (c *v5Client) onConnectAttempt() {
+	c.mu.Lock()
+	// topic aliases only live as long as the network connection
+	c.aliases = make(map[uint16]string)
+	c.mu.Unlock()
+
+	if c.everUp.Load() {
+		if c.opts.OnReconnecting != nil {
+			c.opts.OnReconnecting()
+		}
+	} else if c.opts.OnConnectAttempt != nil {
+		c.opts.OnConnectAttempt()
+	}
+}
+
+func (c *v5Client) onConnectionLost(err error) {
+	c.isUp.Store(false)
+	if c.opts.OnConnectionLost != nil {
+		c.opts.OnConnectionLost(err)
+	}
+}
+
+func (c *v5Client) onPublishReceived(pr paho.PublishReceived) (bool, error) {
+	p := pr.Packet
+	msg := Message{
+		Topic:     p.Topic,
+		Payload:   p.Payload,
+		Qos:       p.QoS,
+		Retained:  p.Retain,
+		Duplicate: p.Duplicate(),
+		MessageId: p.PacketID,
+	}
+
+	c.mu.Lock()
+	if p.Properties != nil {
+		if id := p.Properties.SubscriptionIdentifier; id != nil {
+			msg.Filter = c.filters[*id]
+		}
+		msg.Properties = &Properties{
+			ContentType:            p.Properties.ContentType,
+			ResponseTopic:          p.Properties.ResponseTopic,
+			CorrelationData:        p.Properties.CorrelationData,
+			PayloadFormat:          p.Properties.PayloadFormat,
+			MessageExpiry:          p.Properties.MessageExpiry,
+			TopicAlias:             p.Properties.TopicAlias,
+			SubscriptionIdentifier: p.Properties.SubscriptionIdentifier,
+		}
+		for _, v := range p.Properties.User {
+			msg.Properties.User = append(msg.Properties.User, UserProperty{Key: v.Key, Value: v.Value})
+		}
+
+		if alias := p.Properties.TopicAlias; alias != nil {
+			if msg.Topic != "" {
+				c.aliases[*alias] = msg.Topic
+			} else {
+				msg.Topic = c.aliases[*alias]
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if c.opts.OnMessage != nil {
+		c.opts.OnMessage(msg)
+	}
+	return true, nil
+}
+
+func (c *v5Client) Connect() *Token {
+	t := newToken()
+	ctx, cancel := context.WithCancel(context.Background())
+	cm, err := autopaho.NewConnection(ctx, c.cfg)
+	if err != nil {
+		cancel()
+		t.complete(0, err)
+		return t
+	}
+
+	c.mu.Lock()
+	c.cm = cm
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	go func() {
+		err := cm.AwaitConnection(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		t.complete(0, err)
+	}()
+	return t
+}
+
+func (c *v5Client) Disconnect(quiesce uint) {
+	c.mu.Lock()
+	cm := c.cm
+	cancel := c.cancel
+	c.cm = nil
+	c.mu.Unlock()
+	if cm == nil {
+		return
+	}
+
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), time.Duration(quiesce)*time.Millisecond)
+	defer cancelTimeout()
+	cm.Disconnect(ctx)
+	cancel()
+	c.isUp.Store(false)
+}
+
+func (c *v5Client) IsConnected() bool {
+	return c.isUp.Load()
+}
+
+func (c *v5Client) connectionManager() (*autopaho.ConnectionManager, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cm == nil {
+		return nil, autopaho.ConnectionDownError
+	}
+	return c.cm, nil
+}
+
+func (c *v5Client) Subscribe(topic string, qos byte) *Token {
+	sub := &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: topic, QoS: qos}},
+	}
+	if c.subIdsAvailable.Load() {
+		id := c.subscriptionId(topic)
+		sub.Properties = &paho.SubscribeProperties{SubscriptionIdentifier: &id}
+	}
+
+	return c.request(func(ctx context.Context, cm *autopaho.ConnectionManager) (byte, error) {
+		suback, err := cm.Subscribe(ctx, sub)
+		if suback != nil && len(suback.Reasons) > 0 {
+			code := suback.Reasons[0]
+			if code >= 0x80 {
+				return code, fmt.Errorf("subscribe to %s failed: %s", topic, ReasonString(code))
+			}
+			return code, nil
+		}
+		return 0, err
+	})
+}
+
+// subscriptionId returns the identifier of filter, it is kept when
+// unsubscribing so a filter subscribed to again keeps its identifier
+func (c *v5Client) subscriptionId(filter string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.subIds[filter]
+	if !ok {
+		id = len(c.subIds) + 1
+		c.subIds[filter] = id
+		c.filters[id] = filter
+	}
+	return id
+}
+
+func (c *v5Client) Unsubscribe(topics ...string) *Token {
+	return c.request(func(ctx context.Context, cm *autopaho.ConnectionManager) (byte, error) {
+		unsuback, err := cm.Unsubscribe(ctx, &paho.Unsubscribe{Topics: topics})
+		if unsuback != nil && len(unsuback.Reasons) > 0 {
+			code := unsuback.Reasons[0]
+			if code >= 0x80 {
+				return code, fmt.Errorf("unsubscribe failed: %s", ReasonString(code))
+			}
+			return code, nil
+		}
+		return 0, err
+	})
+}
+
+func (c *v5Client) Publish(msg Message) *Token {
+	p := &paho.Publish{
+		Topic:   msg.Topic,
+		QoS:     msg.Qos,
+		Retain:  msg.Retained,
+		Payload: msg.Payload,
+	}
+	if msg.Properties != nil {
+		p.Properties = &paho.PublishProperties{
+			ContentType:     msg.Properties.ContentType,
+			ResponseTopic:   msg.Properties.ResponseTopic,
+			CorrelationData: msg.Properties.CorrelationData,
+			PayloadFormat:   msg.Properties.PayloadFormat,
+			MessageExpiry:   msg.Properties.MessageExpiry,
+			TopicAlias:      msg.Properties.TopicAlias,
+		}
+		for _, v := range msg.Properties.User {
+			p.Properties.User.Add(v.Key, v.Value)
+		}
+	}
+
+	return c.request(func(ctx context.Context, cm *autopaho.ConnectionManager) (byte, error) {
+		resp, err := cm.Publish(ctx, p)
+		if resp != nil {
+			if resp.ReasonCode >= 0x80 {
+				return resp.ReasonCode, fmt.Errorf("publish to %s failed: %s", msg.Topic, ReasonString(resp.ReasonCode))
+			}
+			return resp.ReasonCode, nil
+		}
+		return 0, err
+	})
+}
+
+// request runs fn against the connection manager in the background and
+// completes the returned token with its result.
+func (c *v5Client) request(fn func(ctx context.Context, cm *autopaho.ConnectionManager) (byte, error)) *Token {
+	t := newToken()
+	cm, err := c.connectionManager()
+	if err != nil {
+		t.complete(0, err)
+		return t
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), kV5RequestTimeout)
+		defer cancel()
+		t.complete(fn(ctx, cm))
+	}()
+	return t
+}
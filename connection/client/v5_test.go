@@ -0,0 +1,46 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestV5FailedAttemptIsNoLostConnection(t *testing.T) {
+	// nothing listens on the port once the listener is closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	connectErrs := make(chan error, 10)
+	lost := make(chan error, 10)
+	c, err := New(Options{
+		ProtocolVersion:  ProtocolVersion5,
+		BrokerUrl:        "mqtt://" + addr,
+		ClientId:         "connect-error-test",
+		OnConnectError:   func(err error) { connectErrs <- err },
+		OnConnectionLost: func(err error) { lost <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Connect()
+	defer c.Disconnect(0)
+
+	select {
+	case <-connectErrs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed attempt not reported")
+	}
+	select {
+	case err := <-lost:
+		t.Errorf("failed attempt reported as a lost connection: %v", err)
+	default:
+	}
+	if c.IsConnected() {
+		t.Error("connected without a broker")
+	}
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v5packets "github.com/eclipse/paho.golang/packets"
+	v3packets "github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/gorilla/websocket"
+)
+
+// wsStream reads and writes MQTT packets over the binary messages of a
+// WebSocket, a packet may span messages
+type wsStream struct {
+	ws *websocket.Conn
+	r  io.Reader
+}
+
+func (s *wsStream) Read(p []byte) (int, error) {
+	for {
+		if s.r == nil {
+			_, r, err := s.ws.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			s.r = r
+		}
+		n, err := s.r.Read(p)
+		if err == io.EOF {
+			s.r = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (s *wsStream) Write(p []byte) (int, error) {
+	return len(p), s.ws.WriteMessage(websocket.BinaryMessage, p)
+}
+
+// serveV3 is a broker for a single MQTT v3.1.1 client that sends every
+// publish back to it
+func serveV3(rw io.ReadWriter) {
+	for {
+		packet, err := v3packets.ReadPacket(rw)
+		if err != nil {
+			return
+		}
+		var reply v3packets.ControlPacket
+		switch p := packet.(type) {
+		case *v3packets.ConnectPacket:
+			reply = v3packets.NewControlPacket(v3packets.Connack)
+		case *v3packets.SubscribePacket:
+			suback := v3packets.NewControlPacket(v3packets.Suback).(*v3packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = p.Qoss
+			reply = suback
+		case *v3packets.PublishPacket:
+			if p.Qos > 0 {
+				puback := v3packets.NewControlPacket(v3packets.Puback).(*v3packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				puback.Write(rw)
+			}
+			echo := v3packets.NewControlPacket(v3packets.Publish).(*v3packets.PublishPacket)
+			echo.TopicName = p.TopicName
+			echo.Payload = p.Payload
+			reply = echo
+		case *v3packets.PingreqPacket:
+			reply = v3packets.NewControlPacket(v3packets.Pingresp)
+		case *v3packets.DisconnectPacket:
+			return
+		}
+		if reply != nil && reply.Write(rw) != nil {
+			return
+		}
+	}
+}
+
+// serveV5 is serveV3 for MQTT v5
+func serveV5(rw io.ReadWriter) {
+	for {
+		packet, err := v5packets.ReadPacket(rw)
+		if err != nil {
+			return
+		}
+		var reply interface {
+			WriteTo(w io.Writer) (int64, error)
+		}
+		switch p := packet.Content.(type) {
+		case *v5packets.Connect:
+			reply = &v5packets.Connack{}
+		case *v5packets.Subscribe:
+			suback := &v5packets.Suback{PacketID: p.PacketID}
+			for _, s := range p.Subscriptions {
+				suback.Reasons = append(suback.Reasons, s.QoS)
+			}
+			reply = suback
+		case *v5packets.Publish:
+			if p.QoS > 0 {
+				(&v5packets.Puback{PacketID: p.PacketID}).WriteTo(rw)
+			}
+			reply = &v5packets.Publish{Topic: p.Topic, Payload: p.Payload}
+		case *v5packets.Pingreq:
+			reply = &v5packets.Pingresp{}
+		case *v5packets.Disconnect:
+			return
+		}
+		if reply != nil {
+			if _, err := reply.WriteTo(rw); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func awaitToken(t *testing.T, token *Token, what string) {
+	t.Helper()
+	select {
+	case <-token.Done():
+		if err := token.Error(); err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: timed out", what)
+	}
+}
+
+func TestWebSocketRoundTrip(t *testing.T) {
+	tests := []struct {
+		protocol string
+		serve    func(rw io.ReadWriter)
+	}{
+		{ProtocolVersion311, serveV3},
+		{ProtocolVersion5, serveV5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			requests := make(chan *http.Request, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case requests <- r:
+				default:
+				}
+				upgrader := websocket.Upgrader{Subprotocols: []string{"mqtt"}}
+				ws, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer ws.Close()
+				tt.serve(&wsStream{ws: ws})
+			}))
+			defer server.Close()
+
+			received := make(chan Message, 1)
+			c, err := New(Options{
+				ProtocolVersion: tt.protocol,
+				BrokerUrl:       "ws" + strings.TrimPrefix(server.URL, "http") + "/custom",
+				ClientId:        "websocket-test",
+				Headers:         http.Header{"X-Api-Key": {"secret"}},
+				OnMessage: func(msg Message) {
+					select {
+					case received <- msg:
+					default:
+					}
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			awaitToken(t, c.Connect(), "connect")
+			defer c.Disconnect(100)
+
+			awaitToken(t, c.Subscribe("test/ws", 1), "subscribe")
+			awaitToken(t, c.Publish(Message{Topic: "test/ws", Payload: []byte("hello"), Qos: 1}), "publish")
+
+			select {
+			case msg := <-received:
+				if msg.Topic != "test/ws" || string(msg.Payload) != "hello" {
+					t.Errorf("received %q on %q, want \"hello\" on \"test/ws\"", msg.Payload, msg.Topic)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no message received")
+			}
+
+			r := <-requests
+			if r.URL.Path != "/custom" {
+				t.Errorf("upgrade request path %q, want /custom", r.URL.Path)
+			}
+			if got := r.Header.Get("X-Api-Key"); got != "secret" {
+				t.Errorf("upgrade request header X-Api-Key %q, want secret", got)
+			}
+		})
+	}
+}
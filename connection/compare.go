@@ -0,0 +1,84 @@
+package connection
+
+import (
+	"errors"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/diff"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+const (
+	diffNone     = iota
+	diffMarked   // against the marked message
+	diffPrevious // against the previous message on the same topic
+)
+
+// toggleMark marks the selected message to diff against, or clears the mark
+// if it is already marked
+func (m *Model) toggleMark() {
+	sub, message, ok := m.selectedMessage()
+	if !ok {
+		return
+	}
+	if m.hasMark && jsonKey(m.markedSub, m.marked) == jsonKey(sub, message) {
+		m.hasMark = false
+		if m.diffMode == diffMarked {
+			m.diffMode = diffNone
+		}
+		return
+	}
+	m.markedSub, m.marked, m.hasMark = sub, message, true
+}
+
+func (m *Model) toggleDiff(mode int) error {
+	if m.diffMode == mode {
+		m.diffMode = diffNone
+		return nil
+	}
+	if mode == diffMarked && !m.hasMark {
+		return errors.New("mark a message with m to diff against first")
+	}
+	m.diffMode = mode
+	return nil
+}
+
+// isMarked reports whether message is the marked one
+func (m Model) isMarked(sub subscription.Model, message subscription.Message) bool {
+	return m.hasMark && jsonKey(m.markedSub, m.marked) == jsonKey(sub, message)
+}
+
+// previousOnTopic returns the message received on the same topic before
+// message, regardless of the search filter
+func previousOnTopic(sub subscription.Model, message subscription.Message) (subscription.Message, bool) {
+	key := jsonKey(sub, message)
+	found := false
+	for i := range sub.Len() {
+		other := sub.At(i)
+		if found && other.RecvTopic() == message.RecvTopic() {
+			return other, true
+		}
+		found = found || jsonKey(sub, other) == key
+	}
+	return subscription.Message{}, false
+}
+
+// diffView compares message with the message chosen by the diff mode, it
+// returns the description of what it is compared with and the diff
+func (m Model) diffView(sub subscription.Model, message subscription.Message) (string, string) {
+	baseSub, base := m.markedSub, m.marked
+	what := "marked"
+	if m.diffMode == diffPrevious {
+		var ok bool
+		baseSub, what = sub, "previous"
+		base, ok = previousOnTopic(sub, message)
+		if !ok {
+			return "diff", "no previous message on " + message.RecvTopic()
+		}
+	}
+
+	description := "diff against " + what + " " + base.RecvAt().Format("15:04:05.000")
+	if base.RecvTopic() != message.RecvTopic() {
+		description += " " + base.RecvTopic()
+	}
+	return description, diff.Render(baseSub.Decode(base, m.session), sub.Decode(message, m.session))
+}
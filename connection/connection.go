@@ -2,16 +2,25 @@ package connection
 
 import (
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"net/url"
+	"net/http"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Broderick-Westrope/charmutils"
+	"github.com/OmegaRelay/mqtt-tui/connection/capture"
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/jsonview"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
 	"github.com/OmegaRelay/mqtt-tui/connection/publish"
+	"github.com/OmegaRelay/mqtt-tui/connection/replay"
 	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/OmegaRelay/mqtt-tui/connection/topictree"
 	"github.com/OmegaRelay/mqtt-tui/form"
 	"github.com/OmegaRelay/mqtt-tui/program"
 	"github.com/OmegaRelay/mqtt-tui/styles"
@@ -24,46 +33,95 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/term"
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
 const (
-	connectionStateConnecting int = iota
+	connectionStateIdle int = iota
+	connectionStateConnecting
 	connectionStateReconnecting
 	connectionStateConnected
 	connectionStateDisconnected
 )
 
 type connectionStateChangeMsg struct {
+	id              string
 	connectionState int
 }
 
-type NewSubMsg subscription.Model
+type connectionErrMsg struct {
+	id  string
+	err error
+}
+
+// historyMsg carries the message log read in the background by loadHistory
+type historyMsg struct {
+	id      string
+	entries []msglog.Entry
+	err     error
+}
+
+// captureMsg carries the messages of the capture file read in the background
+// by loadCapture
+type captureMsg struct {
+	id      string
+	entries []msglog.Entry
+	err     error
+}
+
+// NewSubMsg adds or, while editing, replaces a subscription of the connection
+type NewSubMsg struct {
+	id     string
+	editId string // of the replaced subscription, empty adds sub
+	sub    subscription.Model
+}
 
 type newSubInputs struct {
-	Name   textinput.Model
-	Topic  textinput.Model
-	Qos    form.MultipleChoice
-	Format form.MultipleChoice
+	Name         textinput.Model
+	Topic        textinput.Model
+	Qos          form.MultipleChoice
+	Format       form.MultipleChoice
+	Compression  form.MultipleChoice // applied before the format, auto detects it
+	Extract      textinput.Model     // jq expression shown instead of the payload
+	ProtoFile    textinput.Model     // FileDescriptorSet or .proto file for the protobuf format
+	ProtoMessage textinput.Model
+	MaxMessages  textinput.Model
+	MaxBytes     textinput.Model // total payload size
+	MaxAge       textinput.Model // e.g. 10m, empty keeps messages regardless of age
 }
 
 type newSubModel struct {
-	form form.Model
+	id     string // of the connection
+	editId string // of the edited subscription, empty for a new one
+	form   form.Model
+	err    error // of the last submit, the dialog stays open to fix it
 }
 
 type Data struct {
-	Id           string // UUID used to store subscriptions for persistence
-	Name         string
-	Broker       string
-	Port         int
-	ClientId     string
-	Username     string
-	Password     string
-	UseTls       bool
-	Authenticate bool
-	KeyFilePath  string
-	CertFilePath string
-	CaFilePath   string
+	Id              string // UUID used to store subscriptions for persistence
+	Name            string
+	Broker          string
+	Port            int
+	ClientId        string
+	Username        string
+	Password        string
+	ProtocolVersion string // one of client.ProtocolVersionChoices, empty means v3.1.1
+	Transport       string // one of TransportChoices, empty means derive from UseTls
+	WsPath          string
+	WsHeaders       http.Header
+	UseTls          bool // Deprecated: replaced by Transport, kept to load older connections
+	Authenticate    bool
+	KeyFilePath     string
+	CertFilePath    string
+	CaFilePath      string
+	KeyPassword     string // decrypts an encrypted PKCS#8 client key
+	ServerName      string // overrides the server name used for SNI and certificate verification
+	TlsMinVersion   string // one of TlsVersionChoices
+	TlsMaxVersion   string // one of TlsVersionChoices
+	Alpn            string // comma separated ALPN protocols, e.g. x-amzn-mqtt-ca for AWS IoT on 443
+	TreeFilter      string // subscribed to while the topic tree is open, empty means DefaultTreeFilter
+	MessageLog      bool   // append received messages to <id>.log and reload them on start
+	MessageLogSize  int64  // bytes per log file before it is rotated, 0 means msglog.DefaultMaxSize
+	Capture         string // session recording or NDJSON export shown instead of connecting to a broker
 }
 
 type Model struct {
@@ -74,20 +132,78 @@ type Model struct {
 	keys keyMap
 	help help.Model
 
-	client mqtt.Client
+	client  client.Client
+	router  *router
+	log     *msglog.Log
+	capture []msglog.Entry // messages of Data.Capture, oldest first
+	tree    *topictree.Tree
+	session *subscription.Session
+	err     error // set if the client could not be created from data
+
+	historyLoaded bool // the message log was restored into the subscriptions
+	captureLoaded bool // the capture file was read, or is being read
 
 	connectionState int
-	editSub         bool
 	newSub          tea.Model
 	publish         tea.Model
+	exportDialog    tea.Model
+	replayDialog    tea.Model
+	recorder        *msglog.Log // of the session being recorded
+	player          *replay.Player
+	replayFile      string
+	notice          string // shown in place of the help until the next key press
 	subscriptions   list.Model
-	messageIdx      int
+	treeOpen        bool
+	topicTree       topictree.Model
+	jsonView        jsonview.Model // of the message with jsonKey, once focused
+	jsonKey         string
+	messageIdx      int // selected message, 0 is the newest
+	messageOffset   int // first message visible in the message table
+	hexView         bool
+	expression      string          // ad-hoc extraction, overrides the one of the subscription
+	prompt          textinput.Model // single line prompt in place of the help
+	prompting       int
+	searches        map[string]subSearch // by subscription id
+	diffMode        int
+	markedSub       subscription.Model
+	marked          subscription.Message
+	hasMark         bool
+	matchCache      *matchCache
+	extractCache    *extractCache
+	chartOpen       bool // chart of numeric values instead of the message detail
+	chartWindow     int  // index into chartWindows
+	chartCache      *valueCache
 	spinner         spinner.Model
 	subscriptionIdx int
 }
 
 var spinnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
 
+const (
+	TransportTcp = "tcp"
+	TransportTls = "tls"
+	TransportWs  = "ws"
+	TransportWss = "wss"
+)
+
+var transportChoices = []string{
+	TransportTcp,
+	TransportTls,
+	TransportWs,
+	TransportWss,
+}
+
+var transportSchemes = map[string]string{
+	TransportTcp: "mqtt",
+	TransportTls: "mqtts",
+	TransportWs:  "ws",
+	TransportWss: "wss",
+}
+
+const kDefaultWsPath = "/mqtt"
+
+const DefaultTreeFilter = "#"
+
 func NewModel(data Data) Model {
 	delegate := list.NewDefaultDelegate()
 	items := make([]list.Item, 0)
@@ -98,6 +214,13 @@ func NewModel(data Data) Model {
 		spinner:       spinner.New(spinner.WithSpinner(spinner.Ellipsis), spinner.WithStyle(spinnerStyle)),
 		keys:          keys,
 		help:          help.New(),
+		tree:          topictree.NewTree(),
+		session:       subscription.NewSession(),
+		chartWindow:   defaultChartWindow,
+		chartCache:    &valueCache{},
+		searches:      make(map[string]subSearch),
+		matchCache:    &matchCache{},
+		extractCache:  &extractCache{},
 	}
 	m.subscriptions.Title = "Subscriptions"
 	m.subscriptions.SetShowHelp(false)
@@ -107,57 +230,65 @@ func NewModel(data Data) Model {
 	if err != nil {
 		panic(err)
 	}
-
-	opts := mqtt.NewClientOptions()
-
-	protocol := "mqtt://"
-	if data.UseTls {
-		protocol = "mqtts://"
-	}
-	m.brokerUrl = fmt.Sprintf("%s%s:%d", protocol, m.data.Broker, m.data.Port)
-	opts.AddBroker(m.brokerUrl)
-	opts.SetClientID(m.data.ClientId)
-	if data.Username != "" {
-		opts.SetUsername(data.Username)
-	}
-	if data.Password != "" {
-		opts.SetPassword(data.Password)
+	m.log = msglog.New(logFileName(m.saveFileName), data.MessageLogSize)
+	m.router = &router{tree: m.tree, session: m.session, log: m.log}
+
+	if data.Capture != "" {
+		m.brokerUrl = "file://" + data.Capture
+		m.client = capture.New(client.Options{
+			OnMessage: m.router.route,
+			OnConnect: m.onConnectHandler,
+		})
+		return m.loadSubscriptions()
 	}
 
-	if data.UseTls {
-		tlsCfg := &tls.Config{}
-		if data.KeyFilePath != "" && data.CertFilePath != "" {
-			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
-			cert, _ := tls.LoadX509KeyPair(data.CertFilePath, data.KeyFilePath)
-			tlsCfg = &tls.Config{
-				Certificates: []tls.Certificate{cert},
-				MinVersion:   tls.VersionTLS13,
-			}
+	transport := data.TransportOrDefault()
+	m.brokerUrl = fmt.Sprintf("%s://%s:%d", transportSchemes[transport], m.data.Broker, m.data.Port)
+	var headers http.Header
+	if transport == TransportWs || transport == TransportWss {
+		wsPath := data.WsPath
+		if wsPath == "" {
+			wsPath = kDefaultWsPath
 		}
-		if data.CaFilePath != "" {
-			caPem, err := os.ReadFile(data.CaFilePath)
-			if err == nil && caPem != nil {
-				pool := x509.NewCertPool()
-				ok := pool.AppendCertsFromPEM(caPem)
-				if ok {
-					tlsCfg.ClientCAs = pool
-				}
-			}
+		if !strings.HasPrefix(wsPath, "/") {
+			wsPath = "/" + wsPath
 		}
-		if !data.Authenticate {
-			tlsCfg.InsecureSkipVerify = true
+		m.brokerUrl += wsPath
+		headers = data.WsHeaders
+	}
+
+	var tlsCfg *tls.Config
+	if transport == TransportTls || transport == TransportWss {
+		tlsCfg, err = data.tlsConfig()
+		if err != nil {
+			m.err = fmt.Errorf("invalid TLS configuration for %s: %w", data.Name, err)
+			return m.loadSubscriptions()
 		}
-		opts.SetTLSConfig(tlsCfg)
 	}
 
-	opts.OnConnect = m.onConnectHandler
-	opts.OnConnectionLost = m.onConnectionLostHandler
-	opts.OnReconnecting = m.onReconnectingHandler
-	opts.OnConnectAttempt = m.onConnectAttemptHandler
-	opts.ConnectRetry = true
-	opts.AutoReconnect = true
-	m.client = mqtt.NewClient(opts)
+	m.client, err = client.New(client.Options{
+		ProtocolVersion:  data.ProtocolVersion,
+		BrokerUrl:        m.brokerUrl,
+		ClientId:         data.ClientId,
+		Username:         data.Username,
+		Password:         data.Password,
+		TlsConfig:        tlsCfg,
+		Headers:          headers,
+		OnMessage:        m.router.route,
+		OnConnect:        m.onConnectHandler,
+		OnConnectionLost: m.onConnectionLostHandler,
+		OnReconnecting:   m.onReconnectingHandler,
+		OnConnectAttempt: m.onConnectAttemptHandler,
+		OnConnectError:   m.onConnectErrorHandler,
+	})
+	if err != nil {
+		m.err = err
+	}
 
+	return m.loadSubscriptions()
+}
+
+func (m Model) loadSubscriptions() Model {
 	subsData, err := os.ReadFile(m.saveFileName)
 	if err != nil {
 		return m
@@ -169,16 +300,110 @@ func NewModel(data Data) Model {
 		panic(err)
 	}
 
+	missingId := false
 	for _, sub := range subs {
+		// subscriptions saved before they had an id get one now
+		missingId = missingId || sub.Id == ""
 		newSub := subscription.NewModel(sub)
 		items := m.subscriptions.Items()
 		items = append(items, newSub)
 		m.subscriptions.SetItems(items)
 	}
+	if missingId {
+		m.saveSubscriptions()
+	}
+	m.syncRouter()
 
 	return m
 }
 
+// loadCapture reads the capture file in the background, the messages are
+// handed to the subscriptions and the topic tree once the captureMsg arrives
+func (m Model) loadCapture() tea.Cmd {
+	id, path := m.data.Id, m.data.Capture
+	return func() tea.Msg {
+		entries, err := capture.Load(path)
+		return captureMsg{id: id, entries: entries, err: err}
+	}
+}
+
+// restoreCapture hands the captured messages to a subscription added after
+// the capture was loaded, a session of its own is fed with them so that each
+// is kept with the state it was received in
+func (m Model) restoreCapture(sub subscription.Model) {
+	session := subscription.NewSession()
+	for _, e := range m.capture {
+		obs := session.Observe(e.Message(), e.At)
+		if client.MatchTopic(sub.Data().Topic, e.Topic) {
+			sub.Restore(e.Message(), e.At, obs)
+		}
+	}
+}
+
+// loadHistory reads the message log in the background, the messages are
+// restored into the subscriptions once the historyMsg arrives
+func (m Model) loadHistory() tea.Cmd {
+	id, path := m.data.Id, logFileName(m.saveFileName)
+	return func() tea.Msg {
+		entries, err := msglog.Load(path)
+		return historyMsg{id: id, entries: entries, err: err}
+	}
+}
+
+func TransportChoices() []string {
+	return transportChoices
+}
+
+// TransportOrDefault returns the transport of the connection, falling back
+// to tcp or tls for connections saved before the transport could be chosen
+func (d Data) TransportOrDefault() string {
+	if d.Transport != "" {
+		return d.Transport
+	}
+	if d.UseTls {
+		return TransportTls
+	}
+	return TransportTcp
+}
+
+// ParseHeaders parses HTTP headers given as "Key: Value" pairs separated by
+// semicolons
+func ParseHeaders(s string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q, expected Key: Value", pair)
+		}
+		headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// FormatHeaders is the inverse of ParseHeaders
+func FormatHeaders(headers http.Header) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0)
+	for _, k := range keys {
+		for _, v := range headers[k] {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", k, v))
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
+
 func (m Model) saveSubscriptions() {
 	subscriptionsData := make([]subscription.Data, 0)
 	for _, v := range m.subscriptions.Items() {
@@ -207,26 +432,41 @@ func initSaveFile(id string) (string, error) {
 	return saveFilePath, nil
 }
 
-func (m Model) Title() string       { return m.data.Name }
-func (m Model) Description() string { return fmt.Sprintf("%s:%d", m.data.Broker, m.data.Port) }
+// logFileName is the message log stored next to the subscriptions
+func logFileName(saveFileName string) string {
+	return strings.TrimSuffix(saveFileName, ".json") + ".log"
+}
+
+func (m Model) Title() string { return m.data.Name }
+func (m Model) Description() string {
+	if m.data.Capture != "" {
+		return fmt.Sprintf("%s %s", m.statusView(), path.Base(m.data.Capture))
+	}
+	return fmt.Sprintf("%s %s:%d", m.statusView(), m.data.Broker, m.data.Port)
+}
 func (m Model) FilterValue() string { return m.data.Name }
 
-func (m Model) onConnectHandler(client mqtt.Client) {
-	go program.Program().Send(connectionStateChangeMsg{connectionState: connectionStateConnected})
+func (m Model) onConnectHandler() {
+	go program.Program().Send(connectionStateChangeMsg{id: m.data.Id, connectionState: connectionStateConnected})
 }
 
-func (m Model) onConnectionLostHandler(client mqtt.Client, err error) {
+func (m Model) onConnectionLostHandler(err error) {
 	go program.SendErrorMsg(fmt.Errorf("lost connection: %w", err))
-	go program.Program().Send(connectionStateChangeMsg{connectionState: connectionStateDisconnected})
+	go program.Program().Send(connectionStateChangeMsg{id: m.data.Id, connectionState: connectionStateDisconnected})
+}
+
+func (m Model) onReconnectingHandler() {
+	go program.Program().Send(connectionStateChangeMsg{id: m.data.Id, connectionState: connectionStateReconnecting})
 }
 
-func (m Model) onReconnectingHandler(client mqtt.Client, opts *mqtt.ClientOptions) {
-	go program.Program().Send(connectionStateChangeMsg{connectionState: connectionStateReconnecting})
+func (m Model) onConnectAttemptHandler() {
+	go program.Program().Send(connectionStateChangeMsg{id: m.data.Id, connectionState: connectionStateConnecting})
 }
 
-func (m Model) onConnectAttemptHandler(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
-	go program.Program().Send(connectionStateChangeMsg{connectionState: connectionStateConnecting})
-	return tlsCfg
+// onConnectErrorHandler reports a failed attempt, the state stays connecting
+// or reconnecting as the client tries again
+func (m Model) onConnectErrorHandler(err error) {
+	go program.SendErrorMsg(fmt.Errorf("could not connect: %w", err))
 }
 
 func (m Model) Data() Data {
@@ -234,23 +474,194 @@ func (m Model) Data() Data {
 }
 
 func (m Model) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+// IsOpen reports whether the connection has been started and not yet been
+// disconnected with Disconnect
+func (m Model) IsOpen() bool {
+	return m.connectionState != connectionStateIdle
+}
+
+// Connect starts connecting to the broker, the connection stays open in the
+// background until Disconnect is called. The capture file or the message log
+// is read first, so that loaded messages are older than the ones received.
+func (m Model) Connect() (Model, tea.Cmd) {
+	if m.err != nil {
+		err := m.err
+		return m, func() tea.Msg { return connectionErrMsg{id: m.data.Id, err: err} }
+	}
+	m.connectionState = connectionStateConnecting
+	if m.data.Capture != "" && !m.captureLoaded {
+		m.captureLoaded = true
+		return m, tea.Batch(m.Init(), m.loadCapture())
+	}
+	if m.data.MessageLog && !m.historyLoaded {
+		m.historyLoaded = true
+		return m, tea.Batch(m.Init(), m.loadHistory())
+	}
+	return m, tea.Batch(m.Init(), m.connect())
+}
+
+func (m Model) connect() tea.Cmd {
 	token := m.client.Connect()
 	go handleTokenErr(token)
-	return m.spinner.Tick
+
+	var cmds []tea.Cmd
+	if m.data.MessageLog {
+		err := m.log.Open()
+		if err != nil {
+			cmds = append(cmds, func() tea.Msg { return program.ErrorMsg{Err: err} })
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m Model) Disconnect() Model {
+	m.stopReplay()
+	if m.recorder != nil {
+		m.toggleRecording()
+	}
+	if m.client != nil && m.IsOpen() {
+		m.client.Disconnect(100)
+	}
+	m.log.Close()
+	// a capture still being read is read again the next time
+	if m.capture == nil {
+		m.captureLoaded = false
+	}
+	m.connectionState = connectionStateIdle
+	return m
+}
+
+func (m Model) statusView() string {
+	style, icon, text := m.status()
+	return style.Render(icon + " " + text)
+}
+
+// StatusIcon is a coloured indicator of the connection state
+func (m Model) StatusIcon() string {
+	style, icon, _ := m.status()
+	return style.Render(icon)
+}
+
+func (m Model) status() (lipgloss.Style, string, string) {
+	switch m.connectionState {
+	case connectionStateConnecting:
+		return styles.ConnectingStyle, "◌", "connecting"
+	case connectionStateReconnecting:
+		return styles.ConnectingStyle, "◌", "reconnecting"
+	case connectionStateConnected:
+		return styles.ConnectedStyle, "●", "connected"
+	case connectionStateDisconnected:
+		return styles.DisconnectedStyle, "○", "disconnected"
+	default:
+		return styles.IdleStyle, "○", "idle"
+	}
+}
+
+// HasDialog reports whether a dialog which takes all key presses is open
+func (m Model) HasDialog() bool {
+	return m.publish != nil || m.newSub != nil || m.exportDialog != nil || m.replayDialog != nil || m.prompting != promptNone
+}
+
+// connectionId is the id of the connection that msg belongs to, for the
+// messages which belong to a single connection
+func connectionId(msg tea.Msg) (string, bool) {
+	switch msg := msg.(type) {
+	case connectionStateChangeMsg:
+		return msg.id, true
+	case connectionErrMsg:
+		return msg.id, true
+	case NewSubMsg:
+		return msg.id, true
+	case exportMsg:
+		return msg.id, true
+	case exportedMsg:
+		return msg.id, true
+	case replayMsg:
+		return msg.id, true
+	case historyMsg:
+		return msg.id, true
+	case captureMsg:
+		return msg.id, true
+	}
+	return "", false
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// every open connection is sent the messages of the others
+	if id, ok := connectionId(msg); ok && id != m.data.Id {
+		return m, nil
+	}
 
+	// dialogs take all key presses but other messages are still handled so
+	// the connection keeps up to date while a dialog is open
+	var dialogCmd tea.Cmd
+	_, isKey := msg.(tea.KeyMsg)
 	switch {
 	case m.publish != nil:
-		var cmd tea.Cmd
-		m.publish, cmd = m.publish.Update(msg)
-		return m, cmd
+		m.publish, dialogCmd = m.publish.Update(msg)
+		if isKey {
+			return m, dialogCmd
+		}
 
 	case m.newSub != nil:
-		var cmd tea.Cmd
-		m.newSub, cmd = m.newSub.Update(msg)
-		return m, cmd
+		m.newSub, dialogCmd = m.newSub.Update(msg)
+		if isKey {
+			return m, dialogCmd
+		}
+
+	case m.exportDialog != nil:
+		m.exportDialog, dialogCmd = m.exportDialog.Update(msg)
+		if isKey {
+			return m, dialogCmd
+		}
+
+	case m.replayDialog != nil:
+		m.replayDialog, dialogCmd = m.replayDialog.Update(msg)
+		if isKey {
+			return m, dialogCmd
+		}
+	}
+	if isKey {
+		m.notice = ""
+	}
+
+	if msg, ok := msg.(connectionErrMsg); ok {
+		go program.SendErrorMsg(msg.err)
+		return nil, nil
+	}
+
+	// the topic tree takes the keys of the subscription and message views
+	var treeCmd tea.Cmd
+	if m.treeOpen {
+		if msg, ok := msg.(tea.KeyMsg); ok && !key.Matches(msg, m.keys.ToggleTree, m.keys.Add, m.keys.OpenPublish, m.keys.Escape, m.keys.Help, m.keys.Quit) {
+			_, height, _ := term.GetSize(0)
+			m.topicTree.SetHeight(height - 5)
+			m.topicTree, treeCmd = m.topicTree.Update(msg)
+			return m, treeCmd
+		}
+		m.topicTree, treeCmd = m.topicTree.Update(msg)
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok && m.prompting != promptNone {
+		return m.updatePrompt(msg)
+	}
+
+	// the JSON view takes the keys until it is left with tab or escape
+	if msg, ok := msg.(tea.KeyMsg); ok && m.detailFocused() {
+		switch {
+		case key.Matches(msg, m.keys.FocusDetail, m.keys.Escape):
+			m.jsonView.Blur()
+		case key.Matches(msg, m.keys.Help):
+			m.help.ShowAll = !m.help.ShowAll
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+		default:
+			return m.updateJsonView(msg)
+		}
+		return m, nil
 	}
 
 	m.subscriptions.Update(msg)
@@ -261,7 +672,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Add):
-			m.newSub = NewSubModel(nil)
+			m.newSub = NewSubModel(m.data.Id, nil)
 			return m, m.newSub.Init()
 		case key.Matches(msg, m.keys.Remove):
 			items := m.subscriptions.Items()
@@ -269,48 +680,82 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 			sub := items[m.subscriptions.GlobalIndex()].(subscription.Model)
-			m.client.Unsubscribe(sub.Data().Topic)
 			m.subscriptions.RemoveItem(m.subscriptions.GlobalIndex())
+			m.syncRouter()
+			m.unsubscribe(sub.Data().Topic)
+			m.messageIdx, m.messageOffset = 0, 0
 			m.saveSubscriptions()
 		case key.Matches(msg, m.keys.Edit):
-			items := m.subscriptions.Items()
-			sub := items[m.subscriptions.GlobalIndex()].(subscription.Model)
+			sub, ok := m.selectedSub()
+			if !ok {
+				break
+			}
 			inputs := newSubInputs{}.Copy(sub)
-			m.newSub = NewSubModel(&inputs)
-			m.editSub = true
+			newSub := NewSubModel(m.data.Id, &inputs)
+			newSub.editId = sub.Data().Id
+			m.newSub = newSub
 			return m, m.newSub.Init()
 		case key.Matches(msg, m.keys.Down):
-			m.messageIdx = 0
+			m.messageIdx, m.messageOffset = 0, 0
 			m.subscriptions.CursorDown()
 		case key.Matches(msg, m.keys.Up):
-			m.messageIdx = 0
+			m.messageIdx, m.messageOffset = 0, 0
 			m.subscriptions.CursorUp()
 		case key.Matches(msg, m.keys.Next):
-			items := m.subscriptions.Items()
-			if len(items) == 0 {
-				break
-			}
-			sub := items[m.subscriptions.GlobalIndex()].(subscription.Model)
-			messages := sub.Messages()
-			if len(messages) == 0 {
-				break
-			}
-			m.messageIdx = max(0, m.messageIdx-1)
-			m.subscriptions.SetItem(m.subscriptions.GlobalIndex(), sub)
+			m.moveMessage(-1)
 		case key.Matches(msg, m.keys.Prev):
-			items := m.subscriptions.Items()
-			if len(items) == 0 {
-				break
+			m.moveMessage(1)
+		case key.Matches(msg, m.keys.PageUp):
+			_, height, _ := term.GetSize(0)
+			m.moveMessage(-messageRows(height))
+		case key.Matches(msg, m.keys.PageDown):
+			_, height, _ := term.GetSize(0)
+			m.moveMessage(messageRows(height))
+		case key.Matches(msg, m.keys.JumpToNewest):
+			m.messageIdx, m.messageOffset = 0, 0
+		case key.Matches(msg, m.keys.ToggleHex):
+			m.hexView = !m.hexView
+		case key.Matches(msg, m.keys.FocusDetail):
+			m.focusDetail()
+		case key.Matches(msg, m.keys.ToggleChart):
+			m.chartOpen = !m.chartOpen
+		case key.Matches(msg, m.keys.ShorterWindow):
+			m.chartWindow = max(m.chartWindow-1, 0)
+		case key.Matches(msg, m.keys.LongerWindow):
+			m.chartWindow = min(m.chartWindow+1, len(chartWindows)-1)
+		case key.Matches(msg, m.keys.Extract):
+			return m, m.openPrompt(promptExpression, "= ", "jq expression applied to the messages shown, empty to clear", m.expression)
+		case key.Matches(msg, m.keys.Search):
+			value := ""
+			if s, ok := m.activeSearch(); ok {
+				value = s.query.String()
 			}
-			sub := items[m.subscriptions.GlobalIndex()].(subscription.Model)
-			messages := sub.Messages()
-			if len(messages) == 0 {
+			return m, m.openPrompt(promptSearch, "/", "text, /regex/, topic:text, since:10m, until:15:04, empty to clear", value)
+		case key.Matches(msg, m.keys.NextMatch):
+			m.moveToMatch(1)
+		case key.Matches(msg, m.keys.PrevMatch):
+			m.moveToMatch(-1)
+		case key.Matches(msg, m.keys.ToggleFilter):
+			m.toggleFilter()
+		case key.Matches(msg, m.keys.Mark):
+			m.toggleMark()
+		case key.Matches(msg, m.keys.DiffMarked):
+			if err := m.toggleDiff(diffMarked); err != nil {
+				return m, func() tea.Msg { return program.ErrorMsg{Err: err} }
+			}
+		case key.Matches(msg, m.keys.DiffPrevious):
+			m.toggleDiff(diffPrevious)
+		case key.Matches(msg, m.keys.ToggleTree):
+			m.treeOpen = !m.treeOpen
+			if !m.treeOpen {
+				m.router.setTreeFilter("")
+				m.unsubscribe(m.treeFilter())
 				break
 			}
-			m.messageIdx = min(len(messages)-1, m.messageIdx+1)
-			m.subscriptions.SetItem(m.subscriptions.GlobalIndex(), sub)
-		case key.Matches(msg, m.keys.JumpToNewest):
-			m.messageIdx = 0
+			m.topicTree = topictree.New(m.tree)
+			m.router.setTreeFilter(m.treeFilter())
+			m.subscribe(m.treeFilter())
+			return m, m.topicTree.Init()
 		case key.Matches(msg, m.keys.OpenPublish):
 			topics := make([]string, 0)
 			for _, sub := range m.subscriptions.Items() {
@@ -320,64 +765,151 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				topics = append(topics, sub.Data().Topic)
 			}
-			m.publish = publish.New(m.client, topics)
+			m.publish = publish.New(m.client, m.data.ProtocolVersion == client.ProtocolVersion5, topics)
 			return m, m.publish.Init()
-		case key.Matches(msg, m.keys.Escape):
-			// deinit
-			for _, item := range m.subscriptions.Items() {
-				item, ok := item.(subscription.Model)
-				if !ok {
-					continue
-				}
-				item.Clear()
+		case key.Matches(msg, m.keys.Export):
+			sub, ok := m.selectedSub()
+			if !ok {
+				break
+			}
+			m.exportDialog = newExportModel(m.data.Id, m.activeExpression(sub))
+			return m, m.exportDialog.Init()
+		case key.Matches(msg, m.keys.Record):
+			return m, m.toggleRecording()
+		case key.Matches(msg, m.keys.Replay):
+			if m.player != nil {
+				m.player.TogglePause()
+				break
 			}
-			m.client.Disconnect(100)
+			m.replayDialog = newReplayModel(m.data.Id)
+			return m, m.replayDialog.Init()
+		case key.Matches(msg, m.keys.StopReplay):
+			m.stopReplay()
+		case key.Matches(msg, m.keys.Escape):
+			// the connection keeps running in the background
 			return nil, nil
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 		}
 
 	case NewSubMsg:
-		newSub := subscription.Model(msg)
-		token := m.client.Subscribe(newSub.Data().Topic, newSub.Data().Qos, newSub.OnPubHandler)
-		go handleTokenErr(token)
-		if m.editSub {
-			m.subscriptions.SetItem(m.subscriptions.GlobalIndex(), newSub)
-			m.editSub = false
+		newSub := msg.sub
+		if idx, old, ok := m.subscriptionById(msg.editId); ok {
+			data := newSub.Data()
+			data.Id = old.Data().Id
+			newSub = subscription.NewModel(data)
+			m.subscriptions.SetItem(idx, newSub)
+			if old.Data().Topic != data.Topic {
+				m.unsubscribe(old.Data().Topic)
+			}
 		} else {
 			items := m.subscriptions.Items()
 			items = append(items, newSub)
 			m.subscriptions.SetItems(items)
 		}
+		m.syncRouter()
+		m.restoreCapture(newSub)
+		m.subscribe(newSub.Data().Topic)
 		m.saveSubscriptions()
 
+	case exportMsg:
+		return m, tea.Batch(dialogCmd, treeCmd, m.exportMessages(msg))
+
+	case exportedMsg:
+		m.notice = fmt.Sprintf("exported %d messages to %s", msg.count, msg.path)
+
+	case replayMsg:
+		m.stopReplay()
+		m, cmd := m.startReplay(msg)
+		return m, tea.Batch(dialogCmd, treeCmd, cmd)
+
+	case replayTickMsg:
+		if msg.player != m.player || m.player == nil {
+			break
+		}
+		if status := m.player.Status(); status.Done {
+			m.notice = fmt.Sprintf("replay of %s finished, %d messages published", m.replayFile, status.Total)
+			m.player = nil
+			break
+		}
+		return m, tea.Batch(dialogCmd, treeCmd, m.replayTick())
+
+	case topictree.SubscribeMsg:
+		if !m.treeOpen || msg.Id != m.topicTree.Id() {
+			break
+		}
+		newSub := NewSubMsg{id: m.data.Id, sub: subscription.NewModel(msg.Data)}
+		return m, tea.Batch(dialogCmd, treeCmd, func() tea.Msg { return newSub })
+
+	case historyMsg:
+		m.router.restore(msg.entries)
+		var cmds []tea.Cmd
+		if msg.err != nil {
+			err := msg.err
+			cmds = append(cmds, func() tea.Msg { return program.ErrorMsg{Err: err} })
+		}
+		// disconnected while the log was read
+		if m.IsOpen() {
+			cmds = append(cmds, m.connect())
+		}
+		return m, tea.Batch(append(cmds, dialogCmd, treeCmd)...)
+
+	case captureMsg:
+		// already read when the connection was opened again while reading
+		if m.capture != nil {
+			break
+		}
+		if msg.err != nil {
+			m = m.Disconnect()
+			err := msg.err
+			return m, tea.Batch(dialogCmd, treeCmd, func() tea.Msg { return program.ErrorMsg{Err: err} })
+		}
+		m.capture = msg.entries
+		m.router.restore(msg.entries)
+		for _, e := range msg.entries {
+			m.tree.Add(e.Message(), e.At)
+		}
+		if m.IsOpen() {
+			return m, tea.Batch(dialogCmd, treeCmd, m.connect())
+		}
+		return m, tea.Batch(dialogCmd, treeCmd)
+
 	case connectionStateChangeMsg:
+		if !m.IsOpen() {
+			break
+		}
 		m.connectionState = msg.connectionState
 		if msg.connectionState == connectionStateConnected {
-			for _, model := range m.subscriptions.Items() {
-				sub, ok := model.(subscription.Model)
-				if ok {
-					token := m.client.Subscribe(sub.Data().Topic, sub.Data().Qos, sub.OnPubHandler)
-					go handleTokenErr(token)
+			subscribed := make(map[string]bool)
+			for _, sub := range m.subscriptionModels() {
+				if filter := sub.Data().Topic; !subscribed[filter] {
+					subscribed[filter] = true
+					m.subscribe(filter)
 				}
 			}
+			if m.treeOpen && !subscribed[m.treeFilter()] {
+				m.subscribe(m.treeFilter())
+			}
 		}
 
 	case subscription.ReceivedMsg:
-		items := m.subscriptions.Items()
-		sub := items[m.subscriptions.GlobalIndex()].(subscription.Model)
-		if msg.Sub.Data().Name == sub.Data().Name && m.messageIdx != 0 {
-			messages := sub.Messages()
-			if len(messages) == 0 {
-				break
-			}
-			m.messageIdx = min(len(messages)-1, m.messageIdx+1)
+		// keep the selected message in place unless following the newest
+		sub, ok := m.selectedSub()
+		if !ok || msg.Sub.Data().Id != sub.Data().Id || (m.messageIdx == 0 && !m.jsonView.Focused()) {
+			break
+		}
+		if s, ok := m.searches[sub.Data().Id]; ok && s.filter && sub.Len() > 0 && !m.matches(sub, sub.At(0), s.query, time.Now()) {
+			// hidden by the filter
+			break
 		}
+		count := m.visibleMessages(sub).Len()
+		m.messageIdx = min(count-1, m.messageIdx+1)
+		m.messageOffset = min(count-1, m.messageOffset+1)
 	}
 
 	var cmd tea.Cmd
 	m.spinner, cmd = m.spinner.Update(msg)
-	return m, cmd
+	return m, tea.Batch(dialogCmd, treeCmd, cmd)
 }
 
 func (m Model) View() string {
@@ -386,7 +918,7 @@ func (m Model) View() string {
 		return m.publish.View()
 	}
 
-	if m.connectionState == connectionStateConnecting {
+	if m.connectionState == connectionStateConnecting || m.connectionState == connectionStateIdle {
 		s = m.connectingView()
 	} else {
 		s = m.defaultView()
@@ -411,7 +943,7 @@ func (m Model) defaultView() string {
 	width, height, _ := term.GetSize(0)
 
 	isBg := false
-	if m.newSub != nil {
+	if m.newSub != nil || m.exportDialog != nil || m.replayDialog != nil {
 		isBg = true
 	}
 	if m.help.ShowAll {
@@ -440,48 +972,43 @@ func (m Model) defaultView() string {
 	clientIdView := borderStyle.Render(clientId.View())
 	leftView := lipgloss.JoinVertical(lipgloss.Top, brokerView, clientIdView, subsListView)
 
-	recvTopic := viewport.New(width-(styles.MenuWidth+19), 1)
-	messageNr := viewport.New(8, 4)
-	recvAt := viewport.New(width-(styles.MenuWidth+19), 1)
-	data := viewport.New(width-(styles.MenuWidth+9), height-13)
-	subItems := m.subscriptions.Items()
-
-	if len(subItems) > 0 {
-		sub, ok := subItems[m.subscriptions.GlobalIndex()].(subscription.Model)
-		if ok {
-			messages := sub.Messages()
-			content := lipgloss.NewStyle().
-				Align(lipgloss.Center, lipgloss.Center).
-				Width(messageNr.Width).
-				Height(messageNr.Height).
-				Render(fmt.Sprintf("%d\n/%d", min(m.messageIdx+1, len(messages)), len(messages)))
-			messageNr.SetContent(content)
-			if len(messages) > 0 {
-				message := messages[m.messageIdx]
-				recvTopic.SetContent(string(message.RecvTopic()))
-				recvAt.SetContent(string(message.RecvAt().String()))
-				data.SetContent(string(message.Data()))
-			}
+	var messagesView string
+	shortHelp := m.keys.ShortHelp()
+	if m.treeOpen {
+		m.topicTree.SetHeight(height - 5)
+		messagesView = m.topicTree.View(borderStyle, width-(styles.MenuWidth+9))
+		shortHelp = append(topictree.Keys.ShortHelp(), m.keys.ToggleTree, m.keys.Help, m.keys.Quit)
+	} else {
+		messagesView = m.messagesView(borderStyle, !isBg, width-(styles.MenuWidth+9), height)
+		if m.detailFocused() {
+			shortHelp = append(jsonview.Keys.ShortHelp(), m.keys.FocusDetail, m.keys.Help, m.keys.Quit)
 		}
 	}
-	recvTopicView := borderStyle.Render(recvTopic.View())
-	recvAtView := borderStyle.Render(recvAt.View())
-	messageNrView := borderStyle.Render(messageNr.View())
-
-	messagesHeaderView := lipgloss.JoinVertical(lipgloss.Left, recvTopicView, recvAtView)
-	messagesHeaderView = lipgloss.JoinHorizontal(lipgloss.Left, messagesHeaderView, messageNrView)
-
-	dataView := borderStyle.Render(data.View())
-	messagesView := lipgloss.JoinVertical(lipgloss.Top, messagesHeaderView, dataView)
 	messagesView = borderStyle.Render(messagesView)
 
 	s := lipgloss.JoinHorizontal(lipgloss.Left, leftView, messagesView)
-	s = lipgloss.JoinVertical(lipgloss.Top, s, m.help.ShortHelpView(m.keys.ShortHelp()))
+	if m.prompting != promptNone {
+		m.prompt.Width = width - 8
+		s = lipgloss.JoinVertical(lipgloss.Top, s, m.prompt.View())
+	} else {
+		line := m.help.ShortHelpView(shortHelp)
+		if m.notice != "" {
+			line = m.notice
+		}
+		if activity := m.activityView(); activity != "" {
+			line = activity + " │ " + line
+		}
+		s = lipgloss.JoinVertical(lipgloss.Top, s, line)
+	}
 
 	if isBg {
 		// add foreground widget
 		if m.newSub != nil {
 			s, _ = charmutils.OverlayCenter(s, m.newSub.View(), false)
+		} else if m.exportDialog != nil {
+			s, _ = charmutils.OverlayCenter(s, m.exportDialog.View(), false)
+		} else if m.replayDialog != nil {
+			s, _ = charmutils.OverlayCenter(s, m.replayDialog.View(), false)
 		} else if m.help.ShowAll {
 			s, _ = charmutils.OverlayCenter(s, styles.FocusedBorderStyle.Render(m.help.View(m.keys)), false)
 		}
@@ -490,7 +1017,46 @@ func (m Model) defaultView() string {
 	return s
 }
 
-func handleTokenErr(token mqtt.Token) {
+// propertiesView renders the MQTT v5 properties of a message as a header for
+// its payload
+func propertiesView(props *client.Properties) string {
+	if props == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if props.ContentType != "" {
+		fmt.Fprintf(&b, "Content-Type: %s\n", props.ContentType)
+	}
+	if props.PayloadFormat != nil {
+		fmt.Fprintf(&b, "Payload-Format: %d\n", *props.PayloadFormat)
+	}
+	if props.MessageExpiry != nil {
+		fmt.Fprintf(&b, "Message-Expiry: %ds\n", *props.MessageExpiry)
+	}
+	if props.TopicAlias != nil {
+		fmt.Fprintf(&b, "Topic-Alias: %d\n", *props.TopicAlias)
+	}
+	if props.ResponseTopic != "" {
+		fmt.Fprintf(&b, "Response-Topic: %s\n", props.ResponseTopic)
+	}
+	if props.CorrelationData != nil {
+		fmt.Fprintf(&b, "Correlation-Data: %q\n", props.CorrelationData)
+	}
+	if props.SubscriptionIdentifier != nil {
+		fmt.Fprintf(&b, "Subscription-Identifier: %d\n", *props.SubscriptionIdentifier)
+	}
+	for _, v := range props.User {
+		fmt.Fprintf(&b, "%s: %s\n", v.Key, v.Value)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func handleTokenErr(token *client.Token) {
 	<-token.Done()
 	err := token.Error()
 	if err != nil {
@@ -498,17 +1064,29 @@ func handleTokenErr(token mqtt.Token) {
 	}
 }
 
-func NewSubModel(inputs *newSubInputs) newSubModel {
-	m := newSubModel{}
+func NewSubModel(id string, inputs *newSubInputs) newSubModel {
+	m := newSubModel{id: id}
 
 	if inputs == nil {
 		inputs = &newSubInputs{
-			Name:   textinput.New(),
-			Topic:  textinput.New(),
-			Qos:    form.NewMultipleChoice(subscription.QosChoices()),
-			Format: form.NewMultipleChoice(subscription.FormatChoices),
+			Name:         textinput.New(),
+			Topic:        textinput.New(),
+			Qos:          form.NewMultipleChoice(subscription.QosChoices()),
+			Format:       form.NewMultipleChoice(subscription.FormatChoices()),
+			Compression:  form.NewMultipleChoice(subscription.CompressionChoices()),
+			Extract:      textinput.New(),
+			ProtoFile:    textinput.New(),
+			ProtoMessage: textinput.New(),
+			MaxMessages:  textinput.New(),
+			MaxBytes:     textinput.New(),
+			MaxAge:       textinput.New(),
 		}
 	}
+	inputs.Extract.Placeholder = "e.g. .sensors[0].temp"
+	inputs.ProtoMessage.Placeholder = "package.Message"
+	inputs.MaxMessages.Placeholder = strconv.Itoa(subscription.DefaultMaxMessages)
+	inputs.MaxBytes.Placeholder = "unlimited"
+	inputs.MaxAge.Placeholder = "unlimited, e.g. 10m"
 
 	m.form = form.New("New Subscription", nil)
 	m.form.SetInputs(inputs)
@@ -520,11 +1098,22 @@ func (m newSubModel) Init() tea.Cmd {
 }
 
 func (m newSubModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg.(type) {
+	switch msg := msg.(type) {
 	case form.SubmitMsg:
-		return nil, m.newSubCmd
+		if msg.Id != m.form.Id() {
+			break
+		}
+		data, err := m.subscriptionData()
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		newSub := NewSubMsg{id: m.id, editId: m.editId, sub: subscription.NewModel(data)}
+		return nil, func() tea.Msg { return newSub }
 	case form.CancelMsg:
-		return nil, nil
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
 	}
 
 	var cmd tea.Cmd
@@ -534,21 +1123,60 @@ func (m newSubModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m newSubModel) View() string {
 	content := m.form.View()
-	width, _, _ := term.GetSize(0)
-	widget := viewport.New(width-4, 20)
+	if m.err != nil {
+		content += "\n" + styles.DisconnectedStyle.Render(m.err.Error())
+	}
+	width, height, _ := term.GetSize(0)
+	widget := viewport.New(width-4, min(lipgloss.Height(content), height-4))
 	widget.SetContent(content)
 	return styles.FocusedBorderStyle.Render(widget.View())
 }
 
-func (m newSubModel) newSubCmd() tea.Msg {
+// subscriptionData validates the inputs of the form
+func (m newSubModel) subscriptionData() (subscription.Data, error) {
 	inputs := m.form.Inputs().(*newSubInputs)
-	sub := subscription.NewModel(subscription.Data{
+	data := subscription.Data{
 		Name:   inputs.Name.Value(),
 		Topic:  inputs.Topic.Value(),
 		Qos:    byte(inputs.Qos.Index()),
 		Format: inputs.Format.Selected(),
-	})
-	return NewSubMsg(sub)
+
+		Compression: inputs.Compression.Selected(),
+		Extract:     strings.TrimSpace(inputs.Extract.Value()),
+
+		ProtoFile:    inputs.ProtoFile.Value(),
+		ProtoMessage: inputs.ProtoMessage.Value(),
+	}
+	if data.Format == "protobuf" && (data.ProtoFile == "" || data.ProtoMessage == "") {
+		return data, fmt.Errorf("the protobuf format needs a proto file and message type")
+	}
+	if data.Extract != "" {
+		if _, err := subscription.CompileExpression(data.Extract); err != nil {
+			return data, err
+		}
+	}
+
+	var err error
+	if v := inputs.MaxMessages.Value(); v != "" {
+		data.MaxMessages, err = strconv.Atoi(v)
+		if err != nil || data.MaxMessages < 0 {
+			return data, fmt.Errorf("invalid max messages %q", v)
+		}
+	}
+	if v := inputs.MaxBytes.Value(); v != "" {
+		data.MaxBytes, err = strconv.Atoi(v)
+		if err != nil || data.MaxBytes < 0 {
+			return data, fmt.Errorf("invalid max bytes %q", v)
+		}
+	}
+	if v := inputs.MaxAge.Value(); v != "" {
+		data.MaxAge, err = time.ParseDuration(v)
+		if err != nil || data.MaxAge < 0 {
+			return data, fmt.Errorf("invalid max age %q", v)
+		}
+	}
+
+	return data, nil
 }
 
 func (m newSubInputs) Copy(sub subscription.Model) newSubInputs {
@@ -561,12 +1189,39 @@ func (m newSubInputs) Copy(sub subscription.Model) newSubInputs {
 
 	m.Qos = form.NewMultipleChoice(subscription.QosChoices())
 	m.Qos.SetIndex(int(data.Qos))
-	m.Format = form.NewMultipleChoice(subscription.FormatChoices)
-	for i, v := range subscription.FormatChoices {
+	m.Format = form.NewMultipleChoice(subscription.FormatChoices())
+	for i, v := range subscription.FormatChoices() {
 		if data.Format != v {
 			continue
 		}
 		m.Format.SetIndex(i)
 	}
+	m.Compression = form.NewMultipleChoice(subscription.CompressionChoices())
+	for i, v := range subscription.CompressionChoices() {
+		if data.Compression != v {
+			continue
+		}
+		m.Compression.SetIndex(i)
+	}
+	m.Extract = textinput.New()
+	m.Extract.SetValue(data.Extract)
+
+	m.ProtoFile = textinput.New()
+	m.ProtoFile.SetValue(data.ProtoFile)
+	m.ProtoMessage = textinput.New()
+	m.ProtoMessage.SetValue(data.ProtoMessage)
+
+	m.MaxMessages = textinput.New()
+	if data.MaxMessages > 0 {
+		m.MaxMessages.SetValue(strconv.Itoa(data.MaxMessages))
+	}
+	m.MaxBytes = textinput.New()
+	if data.MaxBytes > 0 {
+		m.MaxBytes.SetValue(strconv.Itoa(data.MaxBytes))
+	}
+	m.MaxAge = textinput.New()
+	if data.MaxAge > 0 {
+		m.MaxAge.SetValue(data.MaxAge.String())
+	}
 	return m
 }
@@ -0,0 +1,259 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/OmegaRelay/mqtt-tui/styles"
+)
+
+// kMaxLines bounds the line diff, which is quadratic in the number of lines
+const kMaxLines = 2000
+
+// kContext is the number of unchanged lines kept around changes
+const kContext = 3
+
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Changed
+)
+
+// Change is a difference between two JSON documents at a jq path
+type Change struct {
+	Kind Kind
+	Path string
+	Old  string // compact JSON, empty if added
+	New  string // compact JSON, empty if removed
+}
+
+// Render compares before and after, structurally if both are JSON and line
+// by line otherwise, and renders the differences coloured
+func Render(before string, after string) string {
+	a, errA := parseJson(before)
+	b, errB := parseJson(after)
+	if errA == nil && errB == nil {
+		return renderChanges(Json(a, b))
+	}
+	return renderLines(Lines(before, after))
+}
+
+func parseJson(s string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// Json returns the changes from a to b, values decoded by encoding/json
+func Json(a any, b any) []Change {
+	changes := make([]Change, 0)
+	compare(".", a, b, &changes)
+	return changes
+}
+
+func compare(path string, a any, b any, changes *[]Change) {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			av, inA := a[k]
+			bv, inB := b[k]
+			p := memberPath(path, k)
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Kind: Added, Path: p, New: compact(bv)})
+			case !inB:
+				*changes = append(*changes, Change{Kind: Removed, Path: p, Old: compact(av)})
+			default:
+				compare(p, av, bv, changes)
+			}
+		}
+		return
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(a), len(b)) {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(a):
+				*changes = append(*changes, Change{Kind: Added, Path: p, New: compact(b[i])})
+			case i >= len(b):
+				*changes = append(*changes, Change{Kind: Removed, Path: p, Old: compact(a[i])})
+			default:
+				compare(p, a[i], b[i], changes)
+			}
+		}
+		return
+	}
+
+	before, after := compact(a), compact(b)
+	if before != after {
+		*changes = append(*changes, Change{Kind: Changed, Path: path, Old: before, New: after})
+	}
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func memberPath(path string, name string) string {
+	if identifier.MatchString(name) {
+		return strings.TrimSuffix(path, ".") + "." + name
+	}
+	quoted, _ := json.Marshal(name)
+	return path + "[" + string(quoted) + "]"
+}
+
+func compact(v any) string {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func renderChanges(changes []Change) string {
+	if len(changes) == 0 {
+		return styles.IdleStyle.Render("no differences")
+	}
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		switch c.Kind {
+		case Added:
+			lines = append(lines, styles.DiffAddedStyle.Render("+ "+c.Path+": "+c.New))
+		case Removed:
+			lines = append(lines, styles.DiffRemovedStyle.Render("- "+c.Path+": "+c.Old))
+		case Changed:
+			lines = append(lines, styles.DiffChangedStyle.Render("~ "+c.Path+": ")+
+				styles.DiffRemovedStyle.Render(c.Old)+" → "+styles.DiffAddedStyle.Render(c.New))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Line is a line of a line diff
+type Line struct {
+	Kind Kind // Changed for unchanged lines
+	Text string
+}
+
+// Lines returns the line diff from before to after based on their longest
+// common subsequence, inputs longer than kMaxLines are compared as a whole
+func Lines(before string, after string) []Line {
+	a := strings.Split(strings.ReplaceAll(before, "\r\n", "\n"), "\n")
+	b := strings.Split(strings.ReplaceAll(after, "\r\n", "\n"), "\n")
+	if len(a) > kMaxLines || len(b) > kMaxLines {
+		if before == after {
+			return toLines(a, Changed)
+		}
+		return append(toLines(a, Removed), toLines(b, Added)...)
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Kind: Changed, Text: a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Kind: Removed, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Kind: Added, Text: b[j]})
+			j++
+		}
+	}
+	lines = append(lines, toLines(a[i:], Removed)...)
+	return append(lines, toLines(b[j:], Added)...)
+}
+
+func toLines(texts []string, kind Kind) []Line {
+	lines := make([]Line, len(texts))
+	for i, text := range texts {
+		lines[i] = Line{Kind: kind, Text: text}
+	}
+	return lines
+}
+
+// renderLines renders a line diff, long runs of unchanged lines are elided
+func renderLines(lines []Line) string {
+	if !slices.ContainsFunc(lines, func(l Line) bool { return l.Kind != Changed }) {
+		return styles.IdleStyle.Render("no differences")
+	}
+
+	near := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.Kind == Changed {
+			continue
+		}
+		for j := max(i-kContext, 0); j <= min(i+kContext, len(lines)-1); j++ {
+			near[j] = true
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	elided := false
+	for i, l := range lines {
+		if !near[i] {
+			if !elided {
+				out = append(out, styles.IdleStyle.Render("  …"))
+			}
+			elided = true
+			continue
+		}
+		elided = false
+		switch l.Kind {
+		case Added:
+			out = append(out, styles.DiffAddedStyle.Render("+ "+l.Text))
+		case Removed:
+			out = append(out, styles.DiffRemovedStyle.Render("- "+l.Text))
+		default:
+			out = append(out, "  "+l.Text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
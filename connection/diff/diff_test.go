@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJson(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []Change
+	}{
+		{"equal", `{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1}`, []Change{}},
+		{"changed", `{"a":1}`, `{"a":2}`, []Change{{Changed, ".a", "1", "2"}}},
+		{"added and removed", `{"a":1}`, `{"b":true}`, []Change{
+			{Removed, ".a", "1", ""},
+			{Added, ".b", "", "true"},
+		}},
+		{"nested", `{"s":[{"t":1},{"t":2}]}`, `{"s":[{"t":1},{"t":3},{"t":4}]}`, []Change{
+			{Changed, ".s[1].t", "2", "3"},
+			{Added, ".s[2]", "", `{"t":4}`},
+		}},
+		{"quoted member", `{"a b":1}`, `{"a b":"1"}`, []Change{{Changed, `.["a b"]`, "1", `"1"`}}},
+		{"type changed", `{"a":[1]}`, `{"a":{"0":1}}`, []Change{{Changed, ".a", "[1]", `{"0":1}`}}},
+		{"root", `1`, `1.0`, []Change{{Changed, ".", "1", "1.0"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseJson(tt.before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := parseJson(tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Json(a, b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string // a line per Line, prefixed with +, - or a space
+	}{
+		{"equal", "a\nb", "a\nb", " a\n b"},
+		{"changed line", "a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c"},
+		{"appended", "a", "a\nb", " a\n+b"},
+		{"removed", "a\nb", "b", "-a\n b"},
+		{"crlf", "a\r\nb", "a\nb", " a\n b"},
+		{"too long", strings.Repeat("a\n", kMaxLines) + "a", strings.Repeat("a\n", kMaxLines) + "b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Lines(tt.before, tt.after)
+			if tt.want == "" {
+				// compared as a whole
+				if len(lines) != 2*(kMaxLines+1) || lines[0].Kind != Removed || lines[kMaxLines+1].Kind != Added {
+					t.Errorf("%d lines, want every line removed and added again", len(lines))
+				}
+				return
+			}
+
+			got := make([]string, len(lines))
+			for i, l := range lines {
+				prefix := " "
+				switch l.Kind {
+				case Added:
+					prefix = "+"
+				case Removed:
+					prefix = "-"
+				}
+				got[i] = prefix + l.Text
+			}
+			if strings.Join(got, "\n") != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderElidesUnchangedLines(t *testing.T) {
+	before := strings.Repeat("same\n", 20) + "old"
+	after := strings.Repeat("same\n", 20) + "new"
+	lines := strings.Split(Render(before, after), "\n")
+	// the elision mark, kContext unchanged lines and the change
+	if len(lines) != 1+kContext+2 {
+		t.Errorf("rendered %d lines, want %d:\n%s", len(lines), 1+kContext+2, strings.Join(lines, "\n"))
+	}
+	if got := Render(`{"a":1}`, `{ "a": 1 }`); !strings.Contains(got, "no differences") {
+		t.Errorf("equal JSON rendered as %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package connection
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/export"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/OmegaRelay/mqtt-tui/form"
+	"github.com/OmegaRelay/mqtt-tui/program"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	exportShown    = "shown" // the messages in the table, after the search filter
+	exportAll      = "all"
+	exportSelected = "selected"
+)
+
+var exportSelectionChoices = []string{
+	exportShown,
+	exportAll,
+	exportSelected,
+}
+
+type exportInputs struct {
+	Format   form.MultipleChoice
+	Messages form.MultipleChoice
+	Path     textinput.Model // empty writes to the working directory
+	Extract  textinput.Model // jq expression of the extracted column
+}
+
+type exportModel struct {
+	id   string // of the connection, every open connection gets the messages
+	form form.Model
+}
+
+// exportMsg asks the connection to export the messages of the selected
+// subscription
+type exportMsg struct {
+	id        string
+	format    string
+	selection string
+	path      string
+	extract   string
+}
+
+type exportedMsg struct {
+	id    string
+	count int
+	path  string
+}
+
+func newExportModel(id string, expr string) exportModel {
+	inputs := &exportInputs{
+		Format:   form.NewMultipleChoice(export.FormatChoices()),
+		Messages: form.NewMultipleChoice(exportSelectionChoices),
+		Path:     textinput.New(),
+		Extract:  textinput.New(),
+	}
+	inputs.Path.Placeholder = "<subscription>-<time>.<format> in the working directory"
+	inputs.Extract.Placeholder = "e.g. .sensors[0].temp"
+	inputs.Extract.SetValue(expr)
+
+	m := exportModel{id: id, form: form.New("Export Messages", nil)}
+	m.form.SetInputs(inputs)
+	return m
+}
+
+func (m exportModel) Init() tea.Cmd {
+	return m.form.Init()
+}
+
+func (m exportModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case form.SubmitMsg:
+		if msg.Id == m.form.Id() {
+			return nil, m.exportCmd
+		}
+	case form.CancelMsg:
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.Update(msg)
+	return m, cmd
+}
+
+func (m exportModel) View() string {
+	content := m.form.View()
+	width, height, _ := term.GetSize(0)
+	widget := viewport.New(width-4, min(lipgloss.Height(content), height-4))
+	widget.SetContent(content)
+	return styles.FocusedBorderStyle.Render(widget.View())
+}
+
+func (m exportModel) exportCmd() tea.Msg {
+	inputs := m.form.Inputs().(*exportInputs)
+	msg := exportMsg{
+		id:        m.id,
+		format:    inputs.Format.Selected(),
+		selection: inputs.Messages.Selected(),
+		path:      strings.TrimSpace(inputs.Path.Value()),
+		extract:   strings.TrimSpace(inputs.Extract.Value()),
+	}
+	if msg.extract != "" {
+		if _, err := subscription.CompileExpression(msg.extract); err != nil {
+			return program.ErrorMsg{Err: err}
+		}
+	}
+	return msg
+}
+
+// exportMessages writes the messages of the selected subscription in the
+// background, oldest first
+func (m Model) exportMessages(msg exportMsg) tea.Cmd {
+	sub, ok := m.selectedSub()
+	if !ok {
+		return nil
+	}
+
+	var messages []subscription.Message
+	switch msg.selection {
+	case exportAll:
+		messages = sub.Messages()
+	case exportSelected:
+		if _, message, ok := m.selectedMessage(); ok {
+			messages = []subscription.Message{message}
+		}
+	default:
+		shown := m.visibleMessages(sub)
+		for i := range shown.Len() {
+			messages = append(messages, shown.At(i))
+		}
+	}
+	if len(messages) == 0 {
+		return func() tea.Msg { return program.ErrorMsg{Err: fmt.Errorf("no messages to export")} }
+	}
+	messages = slices.Clone(messages)
+	slices.Reverse(messages)
+
+	path := expandHome(msg.path)
+	if path == "" {
+		path = export.DefaultPath(sub.Data().Name, msg.format, time.Now())
+	}
+	var extract export.Extractor
+	if msg.extract != "" {
+		session := m.session
+		extract = func(message subscription.Message) ([]any, error) {
+			return sub.Evaluate(message, session, msg.extract)
+		}
+	}
+
+	return func() tea.Msg {
+		err := export.Write(path, msg.format, messages, extract)
+		if err != nil {
+			return program.ErrorMsg{Err: err}
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return exportedMsg{id: msg.id, count: len(messages), path: path}
+	}
+}
+
+// expandHome replaces a leading ~ with the home directory of the user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
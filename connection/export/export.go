@@ -0,0 +1,213 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+const (
+	FormatNdjson = "ndjson"
+	FormatCsv    = "csv"
+	FormatRaw    = "raw" // a directory with a file per payload
+)
+
+var formatChoices = []string{
+	FormatNdjson,
+	FormatCsv,
+	FormatRaw,
+}
+
+func FormatChoices() []string {
+	return formatChoices
+}
+
+// Extractor returns the values extracted from a message, e.g. by a jq
+// expression
+type Extractor func(msg subscription.Message) ([]any, error)
+
+// record is a line of an NDJSON export, payloads which are not valid UTF-8
+// are base64 encoded
+type record struct {
+	At         time.Time          `json:"at"`
+	Topic      string             `json:"topic"`
+	Payload    string             `json:"payload"`
+	Encoding   string             `json:"encoding"`
+	Size       int                `json:"size"`
+	Qos        byte               `json:"qos"`
+	Retained   bool               `json:"retained"`
+	Duplicate  bool               `json:"duplicate"`
+	MessageId  uint16             `json:"messageId,omitempty"`
+	Properties *client.Properties `json:"properties,omitempty"`
+	Extracted  []any              `json:"extracted,omitempty"`
+	Error      string             `json:"error,omitempty"` // of the extraction
+}
+
+// Write exports messages in format to path, a file or for raw payloads a
+// directory, in the order given. extract may be nil.
+func Write(path string, format string, messages []subscription.Message, extract Extractor) error {
+	switch format {
+	case FormatNdjson:
+		return writeFile(path, func(w *bufio.Writer) error { return writeNdjson(w, messages, extract) })
+	case FormatCsv:
+		return writeFile(path, func(w *bufio.Writer) error { return writeCsv(w, messages, extract) })
+	case FormatRaw:
+		return writeRaw(path, messages)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Extension is the file extension of format, empty for a directory
+func Extension(format string) string {
+	switch format {
+	case FormatNdjson:
+		return ".ndjson"
+	case FormatCsv:
+		return ".csv"
+	default:
+		return ""
+	}
+}
+
+// DefaultPath is the file or directory in the working directory that an
+// export of the messages of name is written to when no path is given
+func DefaultPath(name string, format string, at time.Time) string {
+	return fmt.Sprintf("%s-%s%s", fileName(name), at.Format("20060102-150405"), Extension(format))
+}
+
+func writeFile(path string, write func(w *bufio.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create export: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write export: %w", err)
+	}
+	return nil
+}
+
+func writeNdjson(w *bufio.Writer, messages []subscription.Message, extract Extractor) error {
+	enc := json.NewEncoder(w)
+	for _, msg := range messages {
+		payload, encoding := encodePayload(msg.Data())
+		r := record{
+			At:         msg.RecvAt(),
+			Topic:      msg.RecvTopic(),
+			Payload:    payload,
+			Encoding:   encoding,
+			Size:       len(msg.Data()),
+			Qos:        msg.Qos(),
+			Retained:   msg.Retained(),
+			Duplicate:  msg.Duplicate(),
+			MessageId:  msg.MessageId(),
+			Properties: msg.Properties(),
+		}
+		if extract != nil {
+			values, err := extract(msg)
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				r.Extracted = values
+			}
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCsv(w *bufio.Writer, messages []subscription.Message, extract Extractor) error {
+	c := csv.NewWriter(w)
+	header := []string{"time", "topic", "qos", "retained", "duplicate", "message_id", "size", "encoding", "payload"}
+	if extract != nil {
+		header = append(header, "extracted")
+	}
+	c.Write(header)
+	for _, msg := range messages {
+		payload, encoding := encodePayload(msg.Data())
+		row := []string{
+			msg.RecvAt().Format(time.RFC3339Nano),
+			msg.RecvTopic(),
+			strconv.Itoa(int(msg.Qos())),
+			strconv.FormatBool(msg.Retained()),
+			strconv.FormatBool(msg.Duplicate()),
+			strconv.Itoa(int(msg.MessageId())),
+			strconv.Itoa(len(msg.Data())),
+			encoding,
+			payload,
+		}
+		if extract != nil {
+			values, err := extract(msg)
+			if err != nil {
+				row = append(row, err.Error())
+			} else {
+				row = append(row, subscription.FormatValues(values, false))
+			}
+		}
+		c.Write(row)
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeRaw writes each payload to its own file in dir, named by position,
+// time and topic so the files sort in the order of the export
+func writeRaw(dir string, messages []subscription.Message) error {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return fmt.Errorf("could not create export directory: %w", err)
+	}
+	for i, msg := range messages {
+		name := fmt.Sprintf("%06d_%s_%s.bin", i+1, msg.RecvAt().Format("20060102T150405.000"), fileName(msg.RecvTopic()))
+		err = os.WriteFile(filepath.Join(dir, name), msg.Data(), 0660)
+		if err != nil {
+			return fmt.Errorf("could not write export: %w", err)
+		}
+	}
+	return nil
+}
+
+// fileName replaces the characters of topic which are unsafe in file names
+func fileName(topic string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/':
+			return '_'
+		case r < ' ' || strings.ContainsRune(`\:*?"<>|`, r):
+			return '-'
+		}
+		return r
+	}, topic)
+	if len(name) > 100 {
+		name = strings.ToValidUTF8(name[:100], "")
+	}
+	return name
+}
+
+func encodePayload(data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), "utf8"
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64"
+}
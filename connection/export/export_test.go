@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+var testAt = time.Date(2025, 8, 10, 12, 0, 0, 0, time.UTC)
+
+// testMessages are a text and a binary message, oldest first
+func testMessages() []subscription.Message {
+	sub := subscription.NewModel(subscription.Data{})
+	sub.Restore(client.Message{Topic: "sensors/1/temp", Payload: []byte(`{"temp":21.5}`), Qos: 1, MessageId: 7}, testAt, subscription.Observation{})
+	sub.Restore(client.Message{Topic: "bin/data", Payload: []byte{0x00, 0xff}, Retained: true}, testAt.Add(time.Second), subscription.Observation{})
+	messages := sub.Messages()
+	return []subscription.Message{messages[1], messages[0]}
+}
+
+func extractTemp(msg subscription.Message) ([]any, error) {
+	if msg.RecvTopic() != "sensors/1/temp" {
+		return nil, errors.New("no temp")
+	}
+	return []any{21.5}, nil
+}
+
+func TestWriteNdjson(t *testing.T) {
+	tests := []struct {
+		name    string
+		extract Extractor
+		want    []string // lines, compared as JSON
+	}{
+		{"without extraction", nil, []string{
+			`{"at":"2025-08-10T12:00:00Z","topic":"sensors/1/temp","payload":"{\"temp\":21.5}","encoding":"utf8","size":13,"qos":1,"retained":false,"duplicate":false,"messageId":7}`,
+			`{"at":"2025-08-10T12:00:01Z","topic":"bin/data","payload":"AP8=","encoding":"base64","size":2,"qos":0,"retained":true,"duplicate":false}`,
+		}},
+		{"with extraction", extractTemp, []string{
+			`{"at":"2025-08-10T12:00:00Z","topic":"sensors/1/temp","payload":"{\"temp\":21.5}","encoding":"utf8","size":13,"qos":1,"retained":false,"duplicate":false,"messageId":7,"extracted":[21.5]}`,
+			`{"at":"2025-08-10T12:00:01Z","topic":"bin/data","payload":"AP8=","encoding":"base64","size":2,"qos":0,"retained":true,"duplicate":false,"error":"no temp"}`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.ndjson")
+			if err := Write(path, FormatNdjson, testMessages(), tt.extract); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			scanner := bufio.NewScanner(file)
+			for i := 0; scanner.Scan(); i++ {
+				if i >= len(tt.want) {
+					t.Fatalf("more than %d lines", len(tt.want))
+				}
+				var got, want any
+				if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				json.Unmarshal([]byte(tt.want[i]), &want)
+				gotJson, _ := json.Marshal(got)
+				wantJson, _ := json.Marshal(want)
+				if string(gotJson) != string(wantJson) {
+					t.Errorf("line %d is\n%s\nwant\n%s", i, gotJson, wantJson)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := Write(path, FormatCsv, testMessages(), extractTemp); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"time", "topic", "qos", "retained", "duplicate", "message_id", "size", "encoding", "payload", "extracted"},
+		{"2025-08-10T12:00:00Z", "sensors/1/temp", "1", "false", "false", "7", "13", "utf8", `{"temp":21.5}`, "21.5"},
+		{"2025-08-10T12:00:01Z", "bin/data", "0", "true", "false", "0", "2", "base64", "AP8=", "no temp"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d is %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestWriteRaw(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "raw")
+	if err := Write(dir, FormatRaw, testMessages(), nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"000001_20250810T120000.000_sensors_1_temp.bin",
+		"000002_20250810T120001.000_bin_data.bin",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("%d files, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Name() != want[i] {
+			t.Errorf("file %d is %s, want %s", i, e.Name(), want[i])
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, want[1]))
+	if err != nil || string(data) != "\x00\xff" {
+		t.Errorf("raw payload %q, %v", data, err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  string
+	}{
+		{"sensors/1/temp", "sensors_1_temp"},
+		{`a:b*c?"d"<e>|f\g`, "a-b-c--d--e--f-g"},
+		{"tab\there", "tab-here"},
+		{strings.Repeat("é", 60), strings.Repeat("é", 50)},
+	}
+	for _, tt := range tests {
+		if got := fileName(tt.topic); got != tt.want {
+			t.Errorf("fileName(%q) is %q, want %q", tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package connection
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/scroll"
+	"github.com/OmegaRelay/mqtt-tui/connection/search"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/charmbracelet/x/term"
+)
+
+// subSearch is the search of a subscription, it either hides the messages
+// which do not match or only marks the ones which do
+type subSearch struct {
+	query  search.Query
+	filter bool
+}
+
+// matchCache keeps whether the topic and payload of messages match the
+// search, the time range is checked each time as it moves with the clock
+type matchCache struct {
+	key     string // subscription and query the results belong to
+	matches map[string]bool
+}
+
+// activeSearch returns the search of the selected subscription
+func (m Model) activeSearch() (subSearch, bool) {
+	sub, ok := m.selectedSub()
+	if !ok {
+		return subSearch{}, false
+	}
+	s, ok := m.searches[sub.Data().Id]
+	return s, ok
+}
+
+func (m *Model) applySearch(value string) error {
+	sub, ok := m.selectedSub()
+	if !ok {
+		return nil
+	}
+	if value == "" {
+		delete(m.searches, sub.Data().Id)
+		m.messageIdx, m.messageOffset = 0, 0
+		return nil
+	}
+
+	q, err := search.Parse(value, time.Now())
+	if err != nil {
+		return err
+	}
+	m.searches[sub.Data().Id] = subSearch{query: q, filter: true}
+	m.messageIdx, m.messageOffset = 0, 0
+	return nil
+}
+
+// matches reports whether message matches the query, the payload is searched
+// as it is shown, after decoding
+func (m Model) matches(sub subscription.Model, message subscription.Message, q search.Query, now time.Time) bool {
+	if !q.MatchTime(message.RecvAt(), now) {
+		return false
+	}
+
+	data := sub.Data()
+	key := strings.Join([]string{data.Id, data.Format, data.Compression, q.String()}, "\x00")
+	cache := m.matchCache
+	if cache.key != key || cache.matches == nil {
+		cache.key = key
+		cache.matches = make(map[string]bool)
+	}
+	id := jsonKey(sub, message)
+	match, ok := cache.matches[id]
+	if !ok {
+		match = q.MatchText(message.RecvTopic(), sub.Decode(message, m.session))
+		cache.matches[id] = match
+	}
+	return match
+}
+
+// messageList is the messages shown in the message table, newest first
+type messageList interface {
+	Len() int
+	At(i int) subscription.Message
+}
+
+// filteredMessages are the messages which match a search filter
+type filteredMessages []subscription.Message
+
+func (f filteredMessages) Len() int                      { return len(f) }
+func (f filteredMessages) At(i int) subscription.Message { return f[i] }
+
+// visibleMessages returns the messages of sub shown in the message table,
+// newest first. Unless a search filter hides some of them they are read from
+// the subscription as needed rather than copied.
+func (m Model) visibleMessages(sub subscription.Model) messageList {
+	s, ok := m.searches[sub.Data().Id]
+	if !ok || !s.filter {
+		return sub
+	}
+	messages := sub.Messages()
+
+	// forget messages which have been dropped from the store
+	if len(m.matchCache.matches) > 2*len(messages)+100 {
+		m.matchCache.matches = nil
+	}
+
+	now := time.Now()
+	visible := make(filteredMessages, 0)
+	for _, message := range messages {
+		if m.matches(sub, message, s.query, now) {
+			visible = append(visible, message)
+		}
+	}
+	return visible
+}
+
+// moveToMatch selects the next matching message towards older messages for a
+// positive delta, only the matches are shown when filtering
+func (m *Model) moveToMatch(delta int) {
+	s, ok := m.activeSearch()
+	if !ok {
+		return
+	}
+	if s.filter {
+		m.moveMessage(delta)
+		return
+	}
+
+	sub, _ := m.selectedSub()
+	messages := m.visibleMessages(sub)
+	now := time.Now()
+	for i := m.messageIdx + delta; i >= 0 && i < messages.Len(); i += delta {
+		if m.matches(sub, messages.At(i), s.query, now) {
+			m.moveMessage(i - m.messageIdx)
+			return
+		}
+	}
+}
+
+// toggleFilter switches between hiding and marking the messages which do not
+// match, keeping the selected message if it is still shown
+func (m *Model) toggleFilter() {
+	sub, message, hasMessage := m.selectedMessage()
+	s, ok := m.searches[sub.Data().Id]
+	if !ok {
+		return
+	}
+	s.filter = !s.filter
+	m.searches[sub.Data().Id] = s
+
+	m.messageIdx, m.messageOffset = 0, 0
+	if !hasMessage {
+		return
+	}
+	selected := jsonKey(sub, message)
+	messages := m.visibleMessages(sub)
+	for i := range messages.Len() {
+		if jsonKey(sub, messages.At(i)) == selected {
+			_, height, _ := term.GetSize(0)
+			m.messageIdx = i
+			m.messageOffset = scroll.Offset(i, 0, messageRows(height))
+			return
+		}
+	}
+}
+
+// searchView describes the search of the selected subscription for the
+// message header
+func (m Model) searchView(sub subscription.Model, messages messageList) string {
+	s, ok := m.searches[sub.Data().Id]
+	if !ok {
+		return ""
+	}
+	if s.filter {
+		return "filter " + s.query.String()
+	}
+	now := time.Now()
+	count := 0
+	for i := range messages.Len() {
+		if m.matches(sub, messages.At(i), s.query, now) {
+			count++
+		}
+	}
+	return fmt.Sprintf("search %s (%d matches)", s.query.String(), count)
+}
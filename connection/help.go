@@ -3,18 +3,38 @@ package connection
 import "github.com/charmbracelet/bubbles/key"
 
 type keyMap struct {
-	Add          key.Binding
-	Remove       key.Binding
-	Edit         key.Binding
-	Up           key.Binding
-	Down         key.Binding
-	Next         key.Binding
-	Prev         key.Binding
-	JumpToNewest key.Binding
-	OpenPublish  key.Binding
-	Escape       key.Binding
-	Help         key.Binding
-	Quit         key.Binding
+	Add           key.Binding
+	Remove        key.Binding
+	Edit          key.Binding
+	Up            key.Binding
+	Down          key.Binding
+	Next          key.Binding
+	Prev          key.Binding
+	PageUp        key.Binding
+	PageDown      key.Binding
+	JumpToNewest  key.Binding
+	ToggleHex     key.Binding
+	FocusDetail   key.Binding
+	Extract       key.Binding
+	ToggleChart   key.Binding
+	Search        key.Binding
+	NextMatch     key.Binding
+	PrevMatch     key.Binding
+	ToggleFilter  key.Binding
+	Mark          key.Binding
+	DiffMarked    key.Binding
+	DiffPrevious  key.Binding
+	ShorterWindow key.Binding
+	LongerWindow  key.Binding
+	OpenPublish   key.Binding
+	Export        key.Binding
+	Record        key.Binding
+	Replay        key.Binding
+	StopReplay    key.Binding
+	ToggleTree    key.Binding
+	Escape        key.Binding
+	Help          key.Binding
+	Quit          key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -23,8 +43,11 @@ func (k keyMap) ShortHelp() []key.Binding {
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.Next, k.Prev, k.JumpToNewest},
-		{k.Add, k.Remove, k.OpenPublish},
+		{k.Up, k.Down, k.Next, k.Prev, k.PageUp, k.PageDown, k.JumpToNewest, k.ToggleHex},
+		{k.FocusDetail, k.Extract, k.ToggleChart, k.ShorterWindow, k.LongerWindow},
+		{k.Search, k.NextMatch, k.PrevMatch, k.ToggleFilter, k.Mark, k.DiffMarked, k.DiffPrevious},
+		{k.Add, k.Remove, k.OpenPublish, k.Export, k.ToggleTree},
+		{k.Record, k.Replay, k.StopReplay},
 		{k.Escape, k.Help, k.Quit},
 	}
 }
@@ -52,23 +75,103 @@ var keys = keyMap{
 	),
 	Next: key.NewBinding(
 		key.WithKeys("left", "h"),
-		key.WithHelp("←/h", "next message"),
+		key.WithHelp("←/h", "newer message"),
 	),
 	Prev: key.NewBinding(
 		key.WithKeys("right", "l"),
-		key.WithHelp("→/l", "previous message"),
+		key.WithHelp("→/l", "older message"),
+	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page of newer messages"),
+	),
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page of older messages"),
 	),
 	JumpToNewest: key.NewBinding(
 		key.WithKeys(" "),
 		key.WithHelp("space", "jumps to newest message"),
 	),
+	ToggleHex: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "toggle hex dump"),
+	),
+	FocusDetail: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "focus JSON message detail"),
+	),
+	Extract: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "extract values with a jq expression"),
+	),
+	ToggleChart: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "toggle chart of numeric values"),
+	),
+	ShorterWindow: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "shorter chart window"),
+	),
+	LongerWindow: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "longer chart window"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search messages"),
+	),
+	NextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next older match"),
+	),
+	PrevMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "next newer match"),
+	),
+	ToggleFilter: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "toggle between filtering and marking matches"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "mark message to diff against"),
+	),
+	DiffMarked: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "toggle diff against marked message"),
+	),
+	DiffPrevious: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "toggle diff against previous message on the topic"),
+	),
 	OpenPublish: key.NewBinding(
 		key.WithKeys("p"),
 		key.WithHelp("p", "opens publishing dialog"),
 	),
+	Export: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "export messages to a file"),
+	),
+	Record: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "start/stop recording a session"),
+	),
+	Replay: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "replay a session, pause/resume while replaying"),
+	),
+	StopReplay: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "stop replay"),
+	),
+	ToggleTree: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "toggle topic tree"),
+	),
 	Escape: key.NewBinding(
 		key.WithKeys("esc"),
-		key.WithHelp("esc", "return to connections overview"),
+		key.WithHelp("esc", "return to connections overview, stays connected"),
 	),
 	Help: key.NewBinding(
 		key.WithKeys("?"),
@@ -0,0 +1,80 @@
+package jsonview
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keyMap struct {
+	Up          key.Binding
+	Down        key.Binding
+	PageUp      key.Binding
+	PageDown    key.Binding
+	Top         key.Binding
+	Bottom      key.Binding
+	Toggle      key.Binding
+	Expand      key.Binding
+	Collapse    key.Binding
+	ExpandAll   key.Binding
+	CollapseAll key.Binding
+	CopyPath    key.Binding
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Toggle, k.ExpandAll, k.CollapseAll, k.CopyPath}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.Toggle, k.Expand, k.Collapse, k.ExpandAll, k.CollapseAll, k.CopyPath},
+	}
+}
+
+var Keys = keyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "move up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "move down"),
+	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page down"),
+	),
+	Top: key.NewBinding(
+		key.WithKeys("home"),
+		key.WithHelp("home", "go to start"),
+	),
+	Bottom: key.NewBinding(
+		key.WithKeys("end"),
+		key.WithHelp("end", "go to end"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter/space", "fold/unfold"),
+	),
+	Expand: key.NewBinding(
+		key.WithKeys("right", "l"),
+		key.WithHelp("→/l", "unfold"),
+	),
+	Collapse: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "fold, or fold parent"),
+	),
+	ExpandAll: key.NewBinding(
+		key.WithKeys("+"),
+		key.WithHelp("+", "unfold all"),
+	),
+	CollapseAll: key.NewBinding(
+		key.WithKeys("-"),
+		key.WithHelp("-", "fold all"),
+	),
+	CopyPath: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy path"),
+	),
+}
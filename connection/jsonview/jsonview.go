@@ -0,0 +1,286 @@
+package jsonview
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/scroll"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// line is a row of the rendered document, containers which are unfolded have
+// a second line for their closing bracket
+type line struct {
+	node  *node
+	close bool
+}
+
+// Model shows a JSON document with syntax highlighting, objects and arrays can
+// be folded and the jq path of the value under the cursor is shown
+type Model struct {
+	root    *node
+	folded  map[string]bool // by path
+	lines   []line
+	cursor  int
+	offset  int
+	height  int
+	focused bool
+	copied  bool // the path was copied since the last move
+
+	highlight *regexp.Regexp // search matches
+}
+
+// New parses data into a Model, an error is returned if it is not valid JSON
+func New(data []byte) (Model, error) {
+	root, err := parse(data)
+	if err != nil {
+		return Model{}, err
+	}
+	m := Model{root: root, folded: make(map[string]bool)}
+	m.lines = flatten(root, m.folded, nil)
+	return m, nil
+}
+
+// IsContainer reports whether data is a JSON object or array, the documents
+// worth showing as a tree
+func IsContainer(data []byte) bool {
+	s := strings.TrimSpace(string(data))
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+func flatten(n *node, folded map[string]bool, lines []line) []line {
+	lines = append(lines, line{node: n})
+	if !n.isContainer() || folded[n.path] || len(n.children) == 0 {
+		return lines
+	}
+	for _, child := range n.children {
+		lines = flatten(child, folded, lines)
+	}
+	return append(lines, line{node: n, close: true})
+}
+
+// SetHeight sets the number of lines shown by View
+func (m *Model) SetHeight(height int) {
+	m.height = height
+	m.offset = scroll.Offset(m.cursor, m.offset, m.height)
+}
+
+// SetHighlight marks the text matched by re in keys and values, nil clears it
+func (m *Model) SetHighlight(re *regexp.Regexp) {
+	m.highlight = re
+}
+
+func (m *Model) Focus() {
+	m.focused = true
+}
+
+func (m *Model) Blur() {
+	m.focused = false
+	m.copied = false
+}
+
+func (m Model) Focused() bool {
+	return m.focused
+}
+
+// Path is the jq path of the value under the cursor
+func (m Model) Path() string {
+	if len(m.lines) == 0 {
+		return ""
+	}
+	return m.lines[m.cursor].node.path
+}
+
+// Copied reports whether the path was copied to the clipboard and the cursor
+// has not moved since
+func (m Model) Copied() bool {
+	return m.copied
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !m.focused || len(m.lines) == 0 {
+		return m, nil
+	}
+
+	m.copied = false
+	current := m.lines[m.cursor].node
+	switch {
+	case key.Matches(keyMsg, Keys.Up):
+		m.cursor--
+	case key.Matches(keyMsg, Keys.Down):
+		m.cursor++
+	case key.Matches(keyMsg, Keys.PageUp):
+		m.cursor -= max(m.height-1, 1)
+	case key.Matches(keyMsg, Keys.PageDown):
+		m.cursor += max(m.height-1, 1)
+	case key.Matches(keyMsg, Keys.Top):
+		m.cursor = 0
+	case key.Matches(keyMsg, Keys.Bottom):
+		m.cursor = len(m.lines) - 1
+	case key.Matches(keyMsg, Keys.Toggle):
+		if current.isContainer() {
+			m.fold(current, !m.folded[current.path])
+		}
+	case key.Matches(keyMsg, Keys.Expand):
+		if current.isContainer() {
+			m.fold(current, false)
+		}
+	case key.Matches(keyMsg, Keys.Collapse):
+		if current.isContainer() && !m.folded[current.path] && len(current.children) > 0 {
+			m.fold(current, true)
+		} else if current.parent != nil {
+			m.fold(current.parent, true)
+		}
+	case key.Matches(keyMsg, Keys.ExpandAll):
+		clear(m.folded)
+		m.relayout(current)
+	case key.Matches(keyMsg, Keys.CollapseAll):
+		// keep the top level visible
+		for _, child := range m.root.children {
+			foldAll(child, m.folded)
+		}
+		for current.depth > 1 {
+			current = current.parent
+		}
+		m.relayout(current)
+	case key.Matches(keyMsg, Keys.CopyPath):
+		m.copied = true
+		return m, copyCmd(m.Path())
+	}
+	m.cursor = min(max(m.cursor, 0), len(m.lines)-1)
+	m.offset = scroll.Offset(m.cursor, m.offset, m.height)
+	return m, nil
+}
+
+func foldAll(n *node, folded map[string]bool) {
+	if !n.isContainer() {
+		return
+	}
+	folded[n.path] = true
+	for _, child := range n.children {
+		foldAll(child, folded)
+	}
+}
+
+// fold folds or unfolds n and moves the cursor onto it
+func (m *Model) fold(n *node, folded bool) {
+	if folded {
+		m.folded[n.path] = true
+	} else {
+		delete(m.folded, n.path)
+	}
+	m.relayout(n)
+}
+
+// relayout recomputes the lines after folding and moves the cursor onto n
+func (m *Model) relayout(n *node) {
+	m.lines = flatten(m.root, m.folded, m.lines[:0])
+	for i, l := range m.lines {
+		if l.node == n && !l.close {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// copyCmd copies path to the system clipboard, falling back to the terminal
+// clipboard escape sequence if there is none, e.g. over ssh
+func copyCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(path); err != nil {
+			termenv.Copy(path)
+		}
+		return nil
+	}
+}
+
+// View renders the visible lines of the document
+func (m Model) View(width int) string {
+	height := max(m.height, 1)
+	offset := scroll.Offset(m.cursor, min(m.offset, m.cursor), height)
+
+	b := strings.Builder{}
+	for i := offset; i < len(m.lines) && i < offset+height; i++ {
+		if i > offset {
+			b.WriteString("\n")
+		}
+		gutter := "  "
+		if m.focused && i == m.cursor {
+			gutter = styles.JsonKeyStyle.Render("▶ ")
+		}
+		b.WriteString(lipgloss.NewStyle().MaxWidth(width).Render(gutter + m.lineView(m.lines[i])))
+	}
+	return b.String()
+}
+
+func (m Model) lineView(l line) string {
+	n := l.node
+	punct := styles.JsonPunctuationStyle.Render
+	s := strings.Repeat("  ", n.depth)
+
+	comma := ""
+	if !n.isLast() {
+		comma = punct(",")
+	}
+	if l.close {
+		return s + punct(closing(n)) + comma
+	}
+
+	if n.parent != nil && n.parent.kind == kindObject {
+		s += styles.Highlight(quote(n.key), m.highlight, styles.JsonKeyStyle) + punct(": ")
+	}
+	switch n.kind {
+	case kindObject, kindArray:
+		open := opening(n)
+		switch {
+		case len(n.children) == 0:
+			s += punct(open + closing(n))
+		case m.folded[n.path]:
+			s += punct(open+"…"+closing(n)) + comma + styles.JsonNullStyle.Render(" "+countView(n))
+			return s
+		default:
+			return s + punct(open)
+		}
+	case kindString:
+		s += styles.Highlight(n.value, m.highlight, styles.JsonStringStyle)
+	case kindNumber:
+		s += styles.Highlight(n.value, m.highlight, styles.JsonNumberStyle)
+	case kindBool:
+		s += styles.Highlight(n.value, m.highlight, styles.JsonBoolStyle)
+	case kindNull:
+		s += styles.Highlight(n.value, m.highlight, styles.JsonNullStyle)
+	}
+	return s + comma
+}
+
+func opening(n *node) string {
+	if n.kind == kindObject {
+		return "{"
+	}
+	return "["
+}
+
+func closing(n *node) string {
+	if n.kind == kindObject {
+		return "}"
+	}
+	return "]"
+}
+
+func countView(n *node) string {
+	unit := "items"
+	if n.kind == kindObject {
+		unit = "keys"
+	}
+	if len(n.children) == 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	return fmt.Sprintf("%d %s", len(n.children), unit)
+}
@@ -0,0 +1,152 @@
+package jsonview
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const testDoc = `{"a":{"b c":[1,{"d":null}]},"e":"x","f":[]}`
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "home":
+		return tea.KeyMsg{Type: tea.KeyHome}
+	case "end":
+		return tea.KeyMsg{Type: tea.KeyEnd}
+	case "pgdown":
+		return tea.KeyMsg{Type: tea.KeyPgDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// paths returns the path of every line, closing brackets as "/"
+func paths(m Model) []string {
+	paths := make([]string, len(m.lines))
+	for i, l := range m.lines {
+		paths[i] = l.node.path
+		if l.close {
+			paths[i] = "/"
+		}
+	}
+	return paths
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		paths []string
+		err   bool
+	}{
+		{"scalar", `21.5`, []string{"."}, false},
+		{"empty object", `{}`, []string{"."}, false},
+		{
+			"nested",
+			testDoc,
+			[]string{".", ".a", `.a["b c"]`, `.a["b c"][0]`, `.a["b c"][1]`, `.a["b c"][1].d`, "/", "/", "/", ".e", ".f", "/"},
+			false,
+		},
+		{"top level array", `[{"x":1}]`, []string{".", ".[0]", ".[0].x", "/", "/"}, false},
+		{"invalid", `{"a":}`, nil, true},
+		{"unclosed", `[1,2`, nil, true},
+		{"data after the value", `{} {}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New([]byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(m); !slices.Equal(got, tt.paths) {
+				t.Errorf("paths %q, want %q", got, tt.paths)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []string
+		path  string // under the cursor afterwards
+		lines int
+		shown string // part of the view
+	}{
+		{"start at the root", nil, ".", 12, ""},
+		{"down", []string{"j", "j"}, `.a["b c"]`, 12, ""},
+		{"up stops at the top", []string{"j", "k", "k"}, ".", 12, ""},
+		{"bottom", []string{"end"}, "/", 12, ""},
+		{"down stops at the bottom", []string{"end", "j"}, "/", 12, ""},
+		{"top", []string{"end", "home"}, ".", 12, ""},
+		{"page down", []string{"pgdown"}, `.a["b c"][0]`, 12, ""},
+		{"fold", []string{"j", "enter"}, ".a", 5, `"a": {…}, 1 key`},
+		{"unfold", []string{"j", "enter", "enter"}, ".a", 12, ""},
+		{"fold array", []string{"j", "j", "h"}, `.a["b c"]`, 7, `"b c": […] 2 items`},
+		{"collapse a scalar folds its parent", []string{"j", "j", "j", "h"}, `.a["b c"]`, 7, ""},
+		{"expand", []string{"j", "h", "l"}, ".a", 12, ""},
+		{"scalars do not fold", []string{"j", "j", "j", "enter"}, `.a["b c"][0]`, 12, ""},
+		{"collapse all keeps the top level", []string{"j", "j", "j", "-"}, ".a", 5, `"a": {…}, 1 key`},
+		{"expand all", []string{"-", "+"}, ".", 12, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New([]byte(testDoc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.SetHeight(4)
+			m.Focus()
+			for _, k := range tt.keys {
+				m, _ = m.Update(keyMsg(k))
+			}
+
+			path := m.Path()
+			if m.lines[m.cursor].close {
+				path = "/"
+			}
+			if path != tt.path {
+				t.Errorf("path %q, want %q", path, tt.path)
+			}
+			if len(m.lines) != tt.lines {
+				t.Errorf("%d lines, want %d: %q", len(m.lines), tt.lines, paths(m))
+			}
+			view := m.View(80)
+			if tt.shown != "" && !strings.Contains(view, tt.shown) {
+				t.Errorf("view does not show %q:\n%s", tt.shown, view)
+			}
+			if !strings.Contains(view, "▶") {
+				t.Errorf("cursor not visible:\n%s", view)
+			}
+			if n := strings.Count(view, "\n") + 1; n > 4 {
+				t.Errorf("%d lines shown, want at most 4", n)
+			}
+		})
+	}
+}
+
+func TestUpdateBlurred(t *testing.T) {
+	m, err := New([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ = m.Update(keyMsg("j"))
+	if m.Path() != "." {
+		t.Errorf("moved to %q without focus", m.Path())
+	}
+	if strings.Contains(m.View(80), "▶") {
+		t.Error("cursor shown without focus")
+	}
+}
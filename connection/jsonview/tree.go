@@ -0,0 +1,129 @@
+package jsonview
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindObject kind = iota
+	kindArray
+	kindString
+	kindNumber
+	kindBool
+	kindNull
+)
+
+// node is a JSON value, object members keep the order of the document
+type node struct {
+	kind     kind
+	key      string // member name if the parent is an object
+	value    string // scalars as they are written in JSON
+	path     string
+	depth    int
+	children []*node
+	parent   *node
+}
+
+func (n *node) isContainer() bool {
+	return n.kind == kindObject || n.kind == kindArray
+}
+
+func (n *node) isLast() bool {
+	return n.parent == nil || n.parent.children[len(n.parent.children)-1] == n
+}
+
+func parse(data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := parseValue(dec, nil, "", ".")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return root, nil
+}
+
+func parseValue(dec *json.Decoder, parent *node, key string, path string) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{key: key, path: path, parent: parent}
+	if parent != nil {
+		n.depth = parent.depth + 1
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			n.kind = kindObject
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				name, _ := tok.(string)
+				child, err := parseValue(dec, n, name, memberPath(path, name))
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, child)
+			}
+		case '[':
+			n.kind = kindArray
+			for i := 0; dec.More(); i++ {
+				child, err := parseValue(dec, n, "", fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, child)
+			}
+		}
+		// the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind = kindString
+		n.value = quote(tok)
+	case json.Number:
+		n.kind = kindNumber
+		n.value = tok.String()
+	case bool:
+		n.kind = kindBool
+		n.value = fmt.Sprint(tok)
+	case nil:
+		n.kind = kindNull
+		n.value = "null"
+	}
+	return n, nil
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// memberPath is the jq path of the member name of the object at path
+func memberPath(path string, name string) string {
+	if identifier.MatchString(name) {
+		return strings.TrimSuffix(path, ".") + "." + name
+	}
+	return path + "[" + quote(name) + "]"
+}
+
+func quote(s string) string {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
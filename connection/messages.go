@@ -0,0 +1,363 @@
+package connection
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/jsonview"
+	"github.com/OmegaRelay/mqtt-tui/connection/scroll"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// selectedSub returns the subscription under the cursor of the subscription
+// list, false if there are none
+func (m Model) selectedSub() (subscription.Model, bool) {
+	items := m.subscriptions.Items()
+	idx := m.subscriptions.GlobalIndex()
+	if idx < 0 || idx >= len(items) {
+		return subscription.Model{}, false
+	}
+	sub, ok := items[idx].(subscription.Model)
+	return sub, ok
+}
+
+// subscriptionById returns the subscription with id and its index in the
+// list, false if there is none
+func (m Model) subscriptionById(id string) (int, subscription.Model, bool) {
+	for i, item := range m.subscriptions.Items() {
+		if sub, ok := item.(subscription.Model); ok && sub.Data().Id == id {
+			return i, sub, true
+		}
+	}
+	return -1, subscription.Model{}, false
+}
+
+// messageRows is the number of messages visible in the message table
+func messageRows(height int) int {
+	return max(3, (height-5)/3)
+}
+
+// detailHeight is the height of the detail pane below the message table
+func detailHeight(height int) int {
+	return max(1, height-messageRows(height)-13)
+}
+
+// moveMessage moves the message selection by delta, towards older messages
+// for a positive delta, and scrolls the table to keep it visible
+func (m *Model) moveMessage(delta int) {
+	sub, ok := m.selectedSub()
+	if !ok {
+		return
+	}
+	count := m.visibleMessages(sub).Len()
+	if count == 0 {
+		m.messageIdx, m.messageOffset = 0, 0
+		return
+	}
+	m.messageIdx = min(max(m.messageIdx+delta, 0), count-1)
+
+	_, height, _ := term.GetSize(0)
+	m.messageOffset = scroll.Offset(m.messageIdx, m.messageOffset, messageRows(height))
+}
+
+// messagesView renders the message table of the selected subscription above
+// the detail pane of the selected message, height is the terminal height
+func (m Model) messagesView(borderStyle lipgloss.Style, focused bool, width int, height int) string {
+	var messages messageList = filteredMessages(nil)
+	sub, ok := m.selectedSub()
+	if ok {
+		messages = m.visibleMessages(sub)
+	}
+	count := messages.Len()
+	idx := min(m.messageIdx, max(count-1, 0))
+
+	rows := messageRows(height)
+	offset := scroll.Offset(idx, min(m.messageOffset, idx), rows)
+	tableView := borderStyle.Render(m.messageTable(sub, messages, idx, offset, rows, width, focused))
+
+	header := viewport.New(width, 1)
+	detail := viewport.New(width, detailHeight(height))
+	if m.chartOpen && ok {
+		fields, content := m.chartView(sub, messages, width, detailHeight(height))
+		header.SetContent(fields)
+		detail.SetContent(content)
+	} else if count > 0 {
+		message := messages.At(idx)
+		s, searching := m.searches[sub.Data().Id]
+		fields := []string{
+			styles.Highlight(message.RecvTopic(), s.query.TopicPattern(), lipgloss.NewStyle()),
+			message.RecvAt().Format("2006-01-02 15:04:05.000"),
+			fmt.Sprintf("QoS %d", message.Qos()),
+		}
+		if message.MessageId() != 0 {
+			fields = append(fields, fmt.Sprintf("id %d", message.MessageId()))
+		}
+		if message.Retained() {
+			fields = append(fields, "retained")
+		}
+		if message.Duplicate() {
+			fields = append(fields, "duplicate")
+		}
+		fields = append(fields, fmt.Sprintf("%d bytes", len(message.Data())))
+
+		payload, compression, err := sub.Decompress(message)
+		if err != nil {
+			payload = message.Data()
+		} else if compression != "" {
+			fields = append(fields, fmt.Sprintf("%s %d → %d bytes", compression, len(message.Data()), len(payload)))
+		}
+
+		// binary payloads would garble the terminal so they are always dumped
+		properties := propertiesView(message.Properties()) + m.extractionView(sub, message)
+		content := sub.Decode(message, m.session)
+		if m.diffMode != diffNone {
+			var description string
+			description, content = m.diffView(sub, message)
+			fields = append(fields, description)
+		} else if m.hexView || !subscription.IsText(content) {
+			content = subscription.HexDump(payload)
+			fields = append(fields, "hex")
+		} else if doc, ok := m.jsonDocument(sub, message, content); ok {
+			doc.SetHeight(detailHeight(height) - strings.Count(properties, "\n"))
+			doc.SetHighlight(s.query.PayloadPattern())
+			content = doc.View(width)
+			if doc.Focused() {
+				path := doc.Path()
+				if doc.Copied() {
+					path += " (copied)"
+				}
+				fields = append(fields, path)
+			}
+		} else {
+			content = strings.ReplaceAll(content, "\r\n", "\n")
+			content = styles.Highlight(content, s.query.PayloadPattern(), lipgloss.NewStyle())
+		}
+		if searching {
+			fields = append(fields, m.searchView(sub, messages))
+		}
+		fields = append(fields, fmt.Sprintf("%d/%d", idx+1, count))
+		header.SetContent(strings.Join(fields, " │ "))
+		detail.SetContent(properties + content)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Top, tableView, borderStyle.Render(header.View()), borderStyle.Render(detail.View()))
+}
+
+// activeExpression is the extraction expression applied to the messages of
+// sub, the ad-hoc one takes precedence
+func (m Model) activeExpression(sub subscription.Model) string {
+	if m.expression != "" {
+		return m.expression
+	}
+	return sub.Data().Extract
+}
+
+// extractCache keeps the results of the extraction expression, which would
+// otherwise be evaluated for every visible message each time it is drawn
+type extractCache struct {
+	key     string // subscription and expression the results belong to
+	results map[string]extraction
+}
+
+type extraction struct {
+	values []any
+	err    error
+}
+
+// evaluate runs expr on message like Evaluate, the result is cached
+func (m Model) evaluate(sub subscription.Model, message subscription.Message, expr string) ([]any, error) {
+	data := sub.Data()
+	key := strings.Join([]string{data.Id, data.Format, data.Compression, expr}, "\x00")
+	cache := m.extractCache
+	// forget messages which have been dropped from the store
+	if cache.key != key || cache.results == nil || len(cache.results) > 2*sub.Len()+100 {
+		cache.key = key
+		cache.results = make(map[string]extraction)
+	}
+	id := jsonKey(sub, message)
+	result, ok := cache.results[id]
+	if !ok {
+		result.values, result.err = sub.Evaluate(message, m.session, expr)
+		cache.results[id] = result
+	}
+	return result.values, result.err
+}
+
+// preview is the payload of message for the message table, or the result of
+// the extraction expression. An expression of the subscription which fails
+// falls back to the payload, an ad-hoc one shows the error.
+func (m Model) preview(sub subscription.Model, message subscription.Message) string {
+	expr := m.activeExpression(sub)
+	if expr == "" {
+		return sub.Preview(message, m.session)
+	}
+	values, err := m.evaluate(sub, message, expr)
+	switch {
+	case err != nil && m.expression == "":
+		return sub.Preview(message, m.session)
+	case err != nil:
+		return subscription.Preview([]byte(err.Error()))
+	}
+	return subscription.Preview([]byte(subscription.FormatValues(values, false)))
+}
+
+// extractionView shows the result of the extraction expression above the
+// payload of the message
+func (m Model) extractionView(sub subscription.Model, message subscription.Message) string {
+	expr := m.activeExpression(sub)
+	if expr == "" {
+		return ""
+	}
+	result := ""
+	values, err := m.evaluate(sub, message, expr)
+	if err != nil {
+		result = err.Error()
+	} else {
+		result = subscription.FormatValues(values, true)
+	}
+	return styles.JsonKeyStyle.Render(expr) + "\n" + result + "\n\n"
+}
+
+// jsonDocument returns the JSON view of a message whose decoded content is a
+// JSON object or array, keeping the folds of the view opened with tab
+func (m Model) jsonDocument(sub subscription.Model, message subscription.Message, content string) (jsonview.Model, bool) {
+	if m.jsonKey != "" && m.jsonKey == jsonKey(sub, message) {
+		return m.jsonView, true
+	}
+	if !jsonview.IsContainer([]byte(content)) {
+		return jsonview.Model{}, false
+	}
+	doc, err := jsonview.New([]byte(content))
+	return doc, err == nil
+}
+
+// jsonKey identifies the message shown by the JSON view
+func jsonKey(sub subscription.Model, message subscription.Message) string {
+	return fmt.Sprintf("%s %s %d", sub.Data().Id, message.RecvTopic(), message.RecvAt().UnixNano())
+}
+
+// selectedMessage returns the subscription and message shown in the detail
+// pane, false if there is none
+func (m Model) selectedMessage() (subscription.Model, subscription.Message, bool) {
+	sub, ok := m.selectedSub()
+	if !ok {
+		return sub, subscription.Message{}, false
+	}
+	messages := m.visibleMessages(sub)
+	count := messages.Len()
+	if count == 0 {
+		return sub, subscription.Message{}, false
+	}
+	return sub, messages.At(min(m.messageIdx, count-1)), true
+}
+
+// focusDetail moves the focus to the JSON view of the selected message, if it
+// is shown as one
+func (m *Model) focusDetail() {
+	sub, message, ok := m.selectedMessage()
+	if !ok || m.hexView || m.chartOpen || m.diffMode != diffNone {
+		return
+	}
+	content := sub.Decode(message, m.session)
+	if !subscription.IsText(content) {
+		return
+	}
+	doc, ok := m.jsonDocument(sub, message, content)
+	if !ok {
+		return
+	}
+	doc.Focus()
+	m.jsonView = doc
+	m.jsonKey = jsonKey(sub, message)
+}
+
+// updateJsonView passes a key press to the focused JSON view
+func (m Model) updateJsonView(msg tea.KeyMsg) (Model, tea.Cmd) {
+	sub, message, _ := m.selectedMessage()
+	_, height, _ := term.GetSize(0)
+	prefix := propertiesView(message.Properties()) + m.extractionView(sub, message)
+	m.jsonView.SetHeight(detailHeight(height) - strings.Count(prefix, "\n"))
+
+	var cmd tea.Cmd
+	m.jsonView, cmd = m.jsonView.Update(msg)
+	return m, cmd
+}
+
+// detailFocused reports whether the JSON view has the focus and still shows
+// the selected message, which may have been dropped by the retention limits
+func (m Model) detailFocused() bool {
+	if !m.jsonView.Focused() {
+		return false
+	}
+	sub, message, ok := m.selectedMessage()
+	return ok && m.jsonKey == jsonKey(sub, message)
+}
+
+// messageTable renders the rows of messages visible from offset, newest
+// first, with the message at idx selected
+func (m Model) messageTable(sub subscription.Model, messages messageList, idx int, offset int, rows int, width int, focused bool) string {
+	columns := []table.Column{
+		{Title: "Time", Width: 13},
+		{Title: "Topic"},
+		{Title: "Size", Width: 7},
+		{Title: "QoS", Width: 3},
+		{Title: "Ret", Width: 3},
+		{Title: "Payload"},
+	}
+	fixed := len(columns) * 2 // cell padding
+	for _, c := range columns {
+		fixed += c.Width
+	}
+	rest := max(width-fixed, 10)
+	columns[1].Width = rest / 3
+	columns[5].Width = rest - columns[1].Width
+
+	// matches are marked when the search does not filter, as is the message
+	// marked for diffing
+	s, marking := m.searches[sub.Data().Id]
+	marking = marking && !s.filter
+	now := time.Now()
+
+	visible := make([]table.Row, 0, rows)
+	for i := offset; i < messages.Len() && i < offset+rows; i++ {
+		message := messages.At(i)
+		retained := ""
+		if message.Retained() {
+			retained = "✓"
+		}
+		at := message.RecvAt().Format("15:04:05.000")
+		switch {
+		case m.isMarked(sub, message):
+			at = "◆" + at
+		case marking && m.matches(sub, message, s.query, now):
+			at = "●" + at
+		case marking || m.hasMark:
+			at = " " + at
+		}
+		visible = append(visible, table.Row{
+			at,
+			message.RecvTopic(),
+			fmt.Sprintf("%d", len(message.Data())),
+			fmt.Sprintf("%d", message.Qos()),
+			retained,
+			m.preview(sub, message),
+		})
+	}
+
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithRows(visible),
+		table.WithWidth(width),
+		table.WithFocused(focused),
+	)
+	t.SetHeight(rows + 1)
+	t.SetCursor(idx - offset)
+	return t.View()
+}
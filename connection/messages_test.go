@@ -0,0 +1,42 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+func TestEvaluateCachesResults(t *testing.T) {
+	m := Model{session: subscription.NewSession(), extractCache: &extractCache{}}
+	sub := subscription.NewModel(subscription.Data{Id: "s", Topic: "a"})
+	at := time.Now()
+	sub.Restore(client.Message{Topic: "a", Payload: []byte(`{"v":1}`)}, at, subscription.Observation{})
+	sub.Restore(client.Message{Topic: "a", Payload: []byte(`{"v":2}`)}, at.Add(time.Millisecond), subscription.Observation{})
+	messages := sub.Messages()
+
+	// now changes between evaluations, so equal results come from the cache
+	evaluate := func(message subscription.Message, expr string) any {
+		t.Helper()
+		values, err := m.evaluate(sub, message, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return values[len(values)-1]
+	}
+	first := evaluate(messages[0], "now")
+	time.Sleep(time.Millisecond)
+	if again := evaluate(messages[0], "now"); again != first {
+		t.Errorf("evaluated again: %v, then %v", first, again)
+	}
+	if other := evaluate(messages[1], "now"); other == first {
+		t.Error("another message got the cached result")
+	}
+	if v := evaluate(messages[0], ".v"); v == first {
+		t.Error("another expression got the cached result")
+	}
+	if again := evaluate(messages[0], "now"); again == first {
+		t.Error("result of a replaced expression still cached")
+	}
+}
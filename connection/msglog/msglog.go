@@ -0,0 +1,246 @@
+package msglog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+)
+
+// DefaultMaxSize is the size a log file grows to before it is rotated when no
+// size is configured
+const DefaultMaxSize = 64 << 20
+
+// kRotatedFiles is the number of rotated files kept besides the current one,
+// named <path>.1 for the newest to <path>.N for the oldest
+const kRotatedFiles = 3
+
+// Entry is a received message as stored in the log, one JSON object per line
+type Entry struct {
+	At         time.Time          `json:"at"`
+	Topic      string             `json:"topic"`
+	Payload    []byte             `json:"payload"`
+	Qos        byte               `json:"qos"`
+	Retained   bool               `json:"retained,omitempty"`
+	Duplicate  bool               `json:"duplicate,omitempty"`
+	MessageId  uint16             `json:"messageId,omitempty"`
+	Properties *client.Properties `json:"properties,omitempty"`
+}
+
+func NewEntry(msg client.Message, at time.Time) Entry {
+	return Entry{
+		At:         at,
+		Topic:      msg.Topic,
+		Payload:    msg.Payload,
+		Qos:        msg.Qos,
+		Retained:   msg.Retained,
+		Duplicate:  msg.Duplicate,
+		MessageId:  msg.MessageId,
+		Properties: msg.Properties,
+	}
+}
+
+// Message returns the message as it was received
+func (e Entry) Message() client.Message {
+	return client.Message{
+		Topic:      e.Topic,
+		Payload:    e.Payload,
+		Qos:        e.Qos,
+		Retained:   e.Retained,
+		Duplicate:  e.Duplicate,
+		MessageId:  e.MessageId,
+		Properties: e.Properties,
+	}
+}
+
+// Log appends received messages to a file, it discards them while it is not
+// open so it can be shared before the connection decides to log
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64 // negative never rotates
+	file     *os.File
+	size     int64
+	appended int
+}
+
+// New returns a closed log, a maxSize of 0 uses DefaultMaxSize and a negative
+// one never rotates the log
+func New(path string, maxSize int64) *Log {
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Log{path: path, maxSize: maxSize}
+}
+
+// Open opens the log for appending, it is a no-op if it is already open
+func (l *Log) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		return nil
+	}
+	l.appended = 0
+	return l.open()
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0660)
+	if err != nil {
+		return fmt.Errorf("could not open message log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not open message log: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *Log) Path() string { return l.path }
+
+// Appended is the number of entries written since the log was opened
+func (l *Log) Appended() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appended
+}
+
+// IsOpen reports whether appended entries are written
+func (l *Log) IsOpen() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file != nil
+}
+
+// Append writes e to the log, rotating it first if it would grow past its
+// maximum size
+func (l *Log) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err == nil {
+		l.appended++
+	}
+	return err
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and starts a
+// new file
+func (l *Log) rotate() error {
+	l.file.Close()
+	l.file = nil
+
+	os.Remove(rotatedPath(l.path, kRotatedFiles))
+	for i := kRotatedFiles - 1; i >= 1; i-- {
+		err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not rotate message log: %w", err)
+		}
+	}
+	err := os.Rename(l.path, rotatedPath(l.path, 1))
+	if err != nil {
+		return fmt.Errorf("could not rotate message log: %w", err)
+	}
+	return l.open()
+}
+
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Load reads the entries of the log at path and its rotated files, oldest
+// first. A line cut short by a crash is skipped.
+func Load(path string) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	for i := kRotatedFiles; i >= 0; i-- {
+		name := path
+		if i > 0 {
+			name = rotatedPath(path, i)
+		}
+		more, err := ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		entries = append(entries, more...)
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// ReadFile reads the entries of a single log file, e.g. a recorded session,
+// in the order they were written
+func ReadFile(name string) ([]Entry, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read message log: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := read(file)
+	if err != nil {
+		return entries, fmt.Errorf("could not read message log %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+func read(r io.Reader) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e Entry
+			if json.Unmarshal(line, &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package msglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEntry(i int) Entry {
+	at := time.Date(2025, 8, 10, 12, 0, i, 0, time.UTC)
+	return Entry{At: at, Topic: fmt.Sprintf("t/%d", i), Payload: []byte("payload")}
+}
+
+func TestRotation(t *testing.T) {
+	line, err := json.Marshal(testEntry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	perFile := int64(len(line) + 1) // fits two entries, every entry has the same size
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		appends int
+		want    []string // topics loaded, oldest first
+		files   int      // rotated files besides the current one
+	}{
+		{"no rotation", 2 * perFile, 2, []string{"t/0", "t/1"}, 0},
+		{"rotated once", 2 * perFile, 3, []string{"t/0", "t/1", "t/2"}, 1},
+		{"all rotated files", 2 * perFile, 8, []string{"t/0", "t/1", "t/2", "t/3", "t/4", "t/5", "t/6", "t/7"}, kRotatedFiles},
+		{"oldest file dropped", 2 * perFile, 10, []string{"t/2", "t/3", "t/4", "t/5", "t/6", "t/7", "t/8", "t/9"}, kRotatedFiles},
+		{"entry larger than the maximum", perFile / 2, 2, []string{"t/0", "t/1"}, 1},
+		{"never rotated", -1, 10, []string{"t/0", "t/1", "t/2", "t/3", "t/4", "t/5", "t/6", "t/7", "t/8", "t/9"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "c1.log")
+			l := New(path, tt.maxSize)
+			if err := l.Append(testEntry(-1)); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Open(); err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.appends {
+				if err := l.Append(testEntry(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if l.Appended() != tt.appends {
+				t.Errorf("appended %d entries, want %d", l.Appended(), tt.appends)
+			}
+			l.Close()
+
+			entries, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			topics := make([]string, len(entries))
+			for i, e := range entries {
+				topics[i] = e.Topic
+			}
+			if strings.Join(topics, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("loaded %v, want %v", topics, tt.want)
+			}
+			for i := 1; i <= kRotatedFiles; i++ {
+				_, err := os.Stat(rotatedPath(path, i))
+				if exists := err == nil; exists != (i <= tt.files) {
+					t.Errorf("rotated file %d exists: %t, want %d rotated files", i, exists, tt.files)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c1.log")
+	var content []byte
+	for i := range 2 {
+		line, _ := json.Marshal(testEntry(i))
+		content = append(content, line...)
+		content = append(content, '\n')
+	}
+	// a line cut short by a crash and an empty line
+	content = append(content, "\n{\"at\":\"2025-08-10T12:00:02Z\",\"top"...)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Topic != "t/1" || !entries[1].At.Equal(testEntry(1).At) {
+		t.Errorf("loaded %+v, want the two complete entries", entries)
+	}
+
+	if entries, err := Load(filepath.Join(t.TempDir(), "missing.log")); err != nil || len(entries) != 0 {
+		t.Errorf("missing log loaded %v, %v, want no entries and no error", entries, err)
+	}
+}
@@ -0,0 +1,142 @@
+package connection
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/chart"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+// chartWindows are the time windows the chart cycles through, 0 shows all
+// stored messages
+var chartWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 0}
+
+const defaultChartWindow = 1
+
+// valueCache keeps the numeric values of messages so the chart does not
+// decode every stored message each time it is drawn
+type valueCache struct {
+	key    string // subscription and expression the values belong to
+	values map[string]cachedValue
+}
+
+type cachedValue struct {
+	value float64
+	ok    bool
+}
+
+func (m Model) chartWindowView() string {
+	window := chartWindows[m.chartWindow]
+	if window == 0 {
+		return "all"
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(window.String(), "0s"), "0m")
+}
+
+// chartPoints returns the numeric values of the messages of sub received
+// within the chart window, either the result of the extraction expression or
+// the payload parsed as a number
+func (m Model) chartPoints(sub subscription.Model, messages messageList, now time.Time) []chart.Point {
+	expr := m.activeExpression(sub)
+	data := sub.Data()
+	key := strings.Join([]string{data.Id, data.Format, data.Compression, expr}, "\x00")
+	cache := m.chartCache
+	previous := cache.values
+	if cache.key != key {
+		previous = nil
+	}
+	values := make(map[string]cachedValue)
+
+	window := chartWindows[m.chartWindow]
+	points := make([]chart.Point, 0)
+	for i := range messages.Len() {
+		// messages are newest first
+		message := messages.At(i)
+		if window > 0 && now.Sub(message.RecvAt()) > window {
+			break
+		}
+		id := jsonKey(sub, message)
+		v, ok := previous[id]
+		if !ok {
+			v.value, v.ok = m.numericValue(sub, message, expr)
+		}
+		values[id] = v
+		if v.ok {
+			points = append(points, chart.Point{At: message.RecvAt(), Value: v.value})
+		}
+	}
+	cache.key, cache.values = key, values
+	return points
+}
+
+// numericValue returns the value of message shown in the chart, NaN and
+// infinities are left out as they would break the scale and the statistics
+func (m Model) numericValue(sub subscription.Model, message subscription.Message, expr string) (float64, bool) {
+	v, ok := m.parseNumericValue(sub, message, expr)
+	return v, ok && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func (m Model) parseNumericValue(sub subscription.Model, message subscription.Message, expr string) (float64, bool) {
+	if expr == "" {
+		content := sub.Decode(message, m.session)
+		v, err := strconv.ParseFloat(strings.TrimSpace(content), 64)
+		return v, err == nil
+	}
+
+	values, err := sub.Evaluate(message, m.session, expr)
+	if err != nil || len(values) == 0 {
+		return 0, false
+	}
+	switch v := values[0].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// chartView renders the header and chart of the numeric values of messages
+func (m Model) chartView(sub subscription.Model, messages messageList, width int, height int) (string, string) {
+	now := time.Now()
+	points := m.chartPoints(sub, messages, now)
+
+	source := m.activeExpression(sub)
+	if source == "" {
+		source = "payload"
+	}
+	fields := []string{source, "window " + m.chartWindowView()}
+	if len(points) == 0 {
+		fields = append(fields, "no numeric values")
+	} else {
+		stats := chart.Summarize(points)
+		fields = append(fields,
+			fmt.Sprintf("n %d", stats.Count),
+			"min "+chart.FormatValue(stats.Min),
+			"max "+chart.FormatValue(stats.Max),
+			"avg "+chart.FormatValue(stats.Avg),
+			"last "+chart.FormatValue(stats.Last),
+		)
+	}
+
+	from := now.Add(-chartWindows[m.chartWindow])
+	if chartWindows[m.chartWindow] == 0 && len(points) > 0 {
+		from = points[len(points)-1].At
+	}
+	return strings.Join(fields, " │ "), chart.Render(points, from, now, width, height)
+}
@@ -0,0 +1,45 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+func TestNumericValue(t *testing.T) {
+	tests := []struct {
+		payload string
+		expr    string
+		want    float64
+		ok      bool
+	}{
+		{"21.5", "", 21.5, true},
+		{" -3 \n", "", -3, true},
+		{"warm", "", 0, false},
+		{"NaN", "", 0, false},
+		{"Inf", "", 0, false},
+		{"-Inf", "", 0, false},
+		{"1e400", "", 0, false},
+		{`{"v":21.5}`, ".v", 21.5, true},
+		{`{"v":"7"}`, ".v", 7, true},
+		{`{"v":"NaN"}`, ".v", 0, false},
+		{`{"v":"-Inf"}`, ".v", 0, false},
+		{`{"v":1e400}`, ".v", 0, false},
+		{`{"v":true}`, ".v", 0, false},
+		{`{}`, ".v", 0, false},
+	}
+
+	m := Model{session: subscription.NewSession()}
+	for _, tt := range tests {
+		t.Run(tt.payload+" "+tt.expr, func(t *testing.T) {
+			sub := subscription.NewModel(subscription.Data{Topic: "a"})
+			sub.Restore(client.Message{Topic: "a", Payload: []byte(tt.payload)}, time.Now(), subscription.Observation{})
+			got, ok := m.numericValue(sub, sub.Messages()[0], tt.expr)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("numericValue = %v, %t, want %v, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
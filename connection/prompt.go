@@ -0,0 +1,67 @@
+package connection
+
+import (
+	"strings"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/OmegaRelay/mqtt-tui/program"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	promptNone = iota
+	promptExpression
+	promptSearch
+	promptRecord
+)
+
+// openPrompt shows a single line prompt in place of the short help
+func (m *Model) openPrompt(kind int, prefix string, placeholder string, value string) tea.Cmd {
+	m.prompting = kind
+	m.prompt = textinput.New()
+	m.prompt.Prompt = prefix
+	m.prompt.Placeholder = placeholder
+	m.prompt.SetValue(value)
+	return m.prompt.Focus()
+}
+
+// updatePrompt handles the key presses while a prompt is open, enter applies
+// the value and escape discards it
+func (m Model) updatePrompt(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.prompting = promptNone
+		return m, nil
+	case tea.KeyEnter:
+		value := strings.TrimSpace(m.prompt.Value())
+		var err error
+		switch m.prompting {
+		case promptExpression:
+			err = m.applyExpression(value)
+		case promptSearch:
+			err = m.applySearch(value)
+		case promptRecord:
+			err = m.startRecording(value)
+		}
+		if err != nil {
+			return m, func() tea.Msg { return program.ErrorMsg{Err: err} }
+		}
+		m.prompting = promptNone
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) applyExpression(expr string) error {
+	if expr != "" {
+		if _, err := subscription.CompileExpression(expr); err != nil {
+			return err
+		}
+	}
+	m.expression = expr
+	return nil
+}
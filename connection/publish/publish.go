@@ -2,7 +2,11 @@ package publish
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
 	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
 	"github.com/OmegaRelay/mqtt-tui/form"
 	"github.com/OmegaRelay/mqtt-tui/program"
@@ -12,7 +16,6 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/x/term"
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
 type inputs struct {
@@ -22,28 +25,73 @@ type inputs struct {
 	Message textarea.Model
 }
 
+type inputsV5 struct {
+	Topic           textinput.Model
+	QoS             form.MultipleChoice
+	Retain          bool
+	ContentType     textinput.Model
+	ResponseTopic   textinput.Model
+	CorrelationData textinput.Model
+	MessageExpiry   textinput.Model // seconds
+	TopicAlias      textinput.Model
+	UserProperties  textinput.Model // key=value pairs separated by commas
+	Message         textarea.Model
+}
+
+type publishResultMsg struct {
+	id         int64
+	topic      string
+	reasonCode byte
+	err        error
+}
+
+var lastId atomic.Int64
+
 type Model struct {
-	client mqtt.Client
+	id     int64 // tells results apart when several connections have a publish dialog open
+	client client.Client
+	isV5   bool
 
-	form form.Model
+	form   form.Model
+	status string
 }
 
-func New(cl mqtt.Client, suggestedTopics []string) Model {
+func New(cl client.Client, isV5 bool, suggestedTopics []string) Model {
 	m := Model{
+		id:     lastId.Add(1),
 		client: cl,
+		isV5:   isV5,
 
 		form: form.New("Publish Message", nil),
 	}
-	i := inputs{
-		Topic:   textinput.New(),
-		QoS:     form.NewMultipleChoice(subscription.QosChoices()),
-		Message: textarea.New(),
-	}
+	topic := textinput.New()
 	if suggestedTopics != nil {
-		i.Topic.ShowSuggestions = true
-		i.Topic.SetSuggestions(suggestedTopics)
+		topic.ShowSuggestions = true
+		topic.SetSuggestions(suggestedTopics)
+	}
+
+	if isV5 {
+		i := inputsV5{
+			Topic:           topic,
+			QoS:             form.NewMultipleChoice(subscription.QosChoices()),
+			ContentType:     textinput.New(),
+			ResponseTopic:   textinput.New(),
+			CorrelationData: textinput.New(),
+			MessageExpiry:   textinput.New(),
+			TopicAlias:      textinput.New(),
+			UserProperties:  textinput.New(),
+			Message:         textarea.New(),
+		}
+		i.UserProperties.Placeholder = "key=value, key2=value2"
+		m.form.SetInputs(&i)
+	} else {
+		i := inputs{
+			Topic:   topic,
+			QoS:     form.NewMultipleChoice(subscription.QosChoices()),
+			Message: textarea.New(),
+		}
+		m.form.SetInputs(&i)
 	}
-	m.form.SetInputs(&i)
 	return m
 }
 
@@ -55,18 +103,97 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.form, cmd = m.form.Update(msg)
 
-	switch msg.(type) {
+	switch msg := msg.(type) {
 	case form.SubmitMsg:
-		i := m.form.Inputs().(*inputs)
-		m.client.Publish(i.Topic.Value(), byte(i.QoS.Index()), i.Retain, i.Message.Value())
-		return nil, nil
+		if msg.Id != m.form.Id() {
+			break
+		}
+		message, err := m.message()
+		if err != nil {
+			m.status = fmt.Sprintf("Not published: %s", err)
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Publishing to %s...", message.Topic)
+		token := m.client.Publish(message)
+		return m, func() tea.Msg {
+			<-token.Done()
+			return publishResultMsg{id: m.id, topic: message.Topic, reasonCode: token.ReasonCode(), err: token.Error()}
+		}
 	case form.CancelMsg:
-		return nil, nil
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
+	case publishResultMsg:
+		if msg.id != m.id {
+			break
+		}
+		switch {
+		case msg.err != nil:
+			m.status = fmt.Sprintf("Failed: %s", msg.err)
+		case m.isV5:
+			m.status = fmt.Sprintf("Published to %s (reason code %s)", msg.topic, client.ReasonString(msg.reasonCode))
+		default:
+			m.status = fmt.Sprintf("Published to %s", msg.topic)
+		}
 	}
 
 	return m, cmd
 }
 
+func (m Model) message() (client.Message, error) {
+	if !m.isV5 {
+		i := m.form.Inputs().(*inputs)
+		return client.Message{
+			Topic:    i.Topic.Value(),
+			Qos:      byte(i.QoS.Index()),
+			Retained: i.Retain,
+			Payload:  []byte(i.Message.Value()),
+		}, nil
+	}
+
+	i := m.form.Inputs().(*inputsV5)
+	props := &client.Properties{
+		ContentType:     i.ContentType.Value(),
+		ResponseTopic:   i.ResponseTopic.Value(),
+		CorrelationData: []byte(i.CorrelationData.Value()),
+	}
+	if v := i.MessageExpiry.Value(); v != "" {
+		expiry, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return client.Message{}, fmt.Errorf("invalid message expiry: %w", err)
+		}
+		tmp := uint32(expiry)
+		props.MessageExpiry = &tmp
+	}
+	if v := i.TopicAlias.Value(); v != "" {
+		alias, err := strconv.ParseUint(v, 10, 16)
+		if err != nil || alias == 0 {
+			return client.Message{}, fmt.Errorf("invalid topic alias %q", v)
+		}
+		tmp := uint16(alias)
+		props.TopicAlias = &tmp
+	}
+	for _, pair := range strings.Split(i.UserProperties.Value(), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return client.Message{}, fmt.Errorf("invalid user property %q, expected key=value", pair)
+		}
+		props.User = append(props.User, client.UserProperty{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v)})
+	}
+
+	return client.Message{
+		Topic:      i.Topic.Value(),
+		Qos:        byte(i.QoS.Index()),
+		Retained:   i.Retain,
+		Payload:    []byte(i.Message.Value()),
+		Properties: props,
+	}, nil
+}
+
 func (m Model) View() string {
 	width, height, err := term.GetSize(0)
 	if err != nil {
@@ -74,8 +201,13 @@ func (m Model) View() string {
 		return ""
 	}
 
+	content := m.form.View()
+	if m.status != "" {
+		content += "\n" + m.status
+	}
+
 	vp := viewport.New(width-2, height-2)
-	vp.SetContent(m.form.View())
+	vp.SetContent(content)
 
 	return styles.FocusedBorderStyle.Render(vp.View())
 
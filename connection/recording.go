@@ -0,0 +1,219 @@
+package connection
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/capture"
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/export"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+	"github.com/OmegaRelay/mqtt-tui/connection/replay"
+	"github.com/OmegaRelay/mqtt-tui/form"
+	"github.com/OmegaRelay/mqtt-tui/program"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+const kReplayRefreshInterval = 250 * time.Millisecond
+
+type replayInputs struct {
+	File       textinput.Model // recorded session
+	Speed      textinput.Model
+	FromPrefix textinput.Model // replaced by ToPrefix in the recorded topics
+	ToPrefix   textinput.Model
+	Loop       bool
+}
+
+type replayModel struct {
+	id   string // of the connection, every open connection gets the messages
+	form form.Model
+}
+
+// replayMsg asks the connection to publish a recorded session
+type replayMsg struct {
+	id      string
+	file    string
+	options replay.Options
+}
+
+type replayTickMsg struct {
+	player *replay.Player
+}
+
+func newReplayModel(id string) replayModel {
+	inputs := &replayInputs{
+		File:       textinput.New(),
+		Speed:      textinput.New(),
+		FromPrefix: textinput.New(),
+		ToPrefix:   textinput.New(),
+	}
+	inputs.File.Placeholder = "session recorded with R or NDJSON export"
+	inputs.Speed.Placeholder = "1, e.g. 10 for 10× as fast"
+	inputs.FromPrefix.Placeholder = "e.g. site1/"
+	inputs.ToPrefix.Placeholder = "e.g. test/site1/"
+
+	m := replayModel{id: id, form: form.New("Replay Session", nil)}
+	m.form.SetInputs(inputs)
+	return m
+}
+
+func (m replayModel) Init() tea.Cmd {
+	return m.form.Init()
+}
+
+func (m replayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case form.SubmitMsg:
+		if msg.Id == m.form.Id() {
+			return nil, m.replayCmd
+		}
+	case form.CancelMsg:
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.Update(msg)
+	return m, cmd
+}
+
+func (m replayModel) View() string {
+	content := m.form.View()
+	width, height, _ := term.GetSize(0)
+	widget := viewport.New(width-4, min(lipgloss.Height(content), height-4))
+	widget.SetContent(content)
+	return styles.FocusedBorderStyle.Render(widget.View())
+}
+
+func (m replayModel) replayCmd() tea.Msg {
+	inputs := m.form.Inputs().(*replayInputs)
+	msg := replayMsg{
+		id:   m.id,
+		file: expandHome(strings.TrimSpace(inputs.File.Value())),
+		options: replay.Options{
+			FromPrefix: inputs.FromPrefix.Value(),
+			ToPrefix:   inputs.ToPrefix.Value(),
+			Loop:       inputs.Loop,
+		},
+	}
+	if msg.file == "" {
+		return program.ErrorMsg{Err: fmt.Errorf("no session file to replay")}
+	}
+	if v := strings.TrimSuffix(strings.TrimSpace(inputs.Speed.Value()), "x"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || speed <= 0 {
+			return program.ErrorMsg{Err: fmt.Errorf("invalid speed %q", inputs.Speed.Value())}
+		}
+		msg.options.Speed = speed
+	}
+	return msg
+}
+
+// toggleRecording stops the recording or asks where to record to
+func (m *Model) toggleRecording() tea.Cmd {
+	if m.recorder == nil {
+		placeholder := export.DefaultPath(m.data.Name+"-session", export.FormatNdjson, time.Now())
+		return m.openPrompt(promptRecord, "record to: ", placeholder, "")
+	}
+
+	m.router.setRecorder(nil)
+	err := m.recorder.Close()
+	if err != nil {
+		return func() tea.Msg { return program.ErrorMsg{Err: err} }
+	}
+	m.notice = fmt.Sprintf("recorded %d messages to %s", m.recorder.Appended(), m.recorder.Path())
+	m.recorder = nil
+	return nil
+}
+
+// startRecording records every message the connection receives to a new
+// session file at path
+func (m *Model) startRecording(path string) error {
+	path = expandHome(path)
+	if path == "" {
+		path = m.prompt.Placeholder
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	recorder := msglog.New(path, -1)
+	err := recorder.Open()
+	if err != nil {
+		return err
+	}
+	m.recorder = recorder
+	m.router.setRecorder(recorder)
+	return nil
+}
+
+// startReplay publishes the session recorded in msg.file to the broker
+func (m Model) startReplay(msg replayMsg) (Model, tea.Cmd) {
+	entries, err := capture.Load(msg.file)
+	if err == nil && len(entries) == 0 {
+		err = fmt.Errorf("%s has no recorded messages", msg.file)
+	}
+	if err != nil {
+		return m, func() tea.Msg { return program.ErrorMsg{Err: err} }
+	}
+
+	cl := m.client
+	m.player = replay.New(entries, func(msg client.Message) {
+		go handleTokenErr(cl.Publish(msg))
+	}, msg.options)
+	m.replayFile = filepath.Base(msg.file)
+	m.player.Start()
+	return m, m.replayTick()
+}
+
+func (m Model) replayTick() tea.Cmd {
+	player := m.player
+	return tea.Tick(kReplayRefreshInterval, func(time.Time) tea.Msg {
+		return replayTickMsg{player: player}
+	})
+}
+
+// stopReplay stops the replay, the messages already published stay published
+func (m *Model) stopReplay() {
+	if m.player == nil {
+		return
+	}
+	m.player.Stop()
+	status := m.player.Status()
+	m.notice = fmt.Sprintf("replay of %s stopped after %d/%d messages", m.replayFile, status.Position, status.Total)
+	m.player = nil
+}
+
+// activityView shows the recording and replay in progress
+func (m Model) activityView() string {
+	activities := make([]string, 0, 2)
+	if m.recorder != nil {
+		activities = append(activities, styles.DisconnectedStyle.Render("●")+fmt.Sprintf(" rec %d", m.recorder.Appended()))
+	}
+	if m.player != nil {
+		status := m.player.Status()
+		icon := "▶"
+		if status.Paused {
+			icon = "⏸"
+		}
+		s := fmt.Sprintf("%s %s %d/%d %s×", icon, m.replayFile, status.Position, status.Total, strconv.FormatFloat(m.player.Speed(), 'f', -1, 64))
+		if status.Pass > 1 {
+			s += fmt.Sprintf(" pass %d", status.Pass)
+		}
+		activities = append(activities, s)
+	}
+	return strings.Join(activities, " │ ")
+}
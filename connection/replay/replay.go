@@ -0,0 +1,198 @@
+package replay
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+)
+
+type Options struct {
+	Speed      float64 // 2 plays twice as fast as recorded, 0 is the same as 1
+	FromPrefix string  // removed from the recorded topics which start with it
+	ToPrefix   string  // added in place of FromPrefix
+	Loop       bool    // start over once the end is reached, until stopped
+}
+
+// Status is the progress of a Player
+type Status struct {
+	Position int // messages published in the current pass
+	Total    int
+	Pass     int // 1 for the first pass
+	Paused   bool
+	Done     bool
+}
+
+// Player publishes recorded messages with the time between them that they
+// were received with, divided by the speed
+type Player struct {
+	entries []msglog.Entry
+	publish func(msg client.Message)
+	opts    Options
+
+	mu      sync.Mutex
+	status  Status
+	pausing chan struct{} // closed when a pause starts
+	resume  chan struct{} // closed when it ends
+	stop    chan struct{}
+	stopped bool
+}
+
+// New returns a player of entries, which have to be in the order they were
+// received, that calls publish for each of them once started
+func New(entries []msglog.Entry, publish func(msg client.Message), opts Options) *Player {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	return &Player{
+		entries: entries,
+		publish: publish,
+		opts:    opts,
+		status:  Status{Total: len(entries), Pass: 1},
+		pausing: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (p *Player) Start() {
+	go p.run()
+}
+
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *Player) Speed() float64 { return p.opts.Speed }
+
+// TogglePause pauses a playing player or resumes a paused one, the message
+// that was waited for keeps the rest of its delay
+func (p *Player) TogglePause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status.Done {
+		return
+	}
+	if p.status.Paused {
+		p.pausing = make(chan struct{})
+		close(p.resume)
+		p.status.Paused = false
+	} else {
+		p.resume = make(chan struct{})
+		close(p.pausing)
+		p.status.Paused = true
+	}
+}
+
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.stopped {
+		close(p.stop)
+		p.stopped = true
+	}
+}
+
+func (p *Player) run() {
+	defer func() {
+		p.mu.Lock()
+		p.status.Done = true
+		p.status.Paused = false
+		p.mu.Unlock()
+	}()
+
+	if len(p.entries) == 0 {
+		return
+	}
+	for {
+		for i, e := range p.entries {
+			delay := time.Duration(0)
+			if i > 0 {
+				delay = time.Duration(float64(e.At.Sub(p.entries[i-1].At)) / p.opts.Speed)
+			}
+			if !p.wait(delay) {
+				return
+			}
+			p.publish(p.message(e))
+
+			p.mu.Lock()
+			p.status.Position = i + 1
+			p.mu.Unlock()
+		}
+		if !p.opts.Loop {
+			return
+		}
+
+		p.mu.Lock()
+		p.status.Position = 0
+		p.status.Pass++
+		p.mu.Unlock()
+	}
+}
+
+// wait sleeps for delay, not counting the time spent paused, and reports
+// false once stopped
+func (p *Player) wait(delay time.Duration) bool {
+	deadline := time.Now().Add(max(delay, 0))
+	for {
+		p.mu.Lock()
+		pausing := p.pausing
+		p.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case <-timer.C:
+			select {
+			case <-pausing:
+				// paused just as the delay ended
+			default:
+				return true
+			}
+		case <-pausing:
+			timer.Stop()
+		case <-p.stop:
+			timer.Stop()
+			return false
+		}
+
+		// resume belongs to the pause which closed pausing, unless that pause
+		// has already ended again
+		p.mu.Lock()
+		resume := p.resume
+		p.mu.Unlock()
+
+		remaining := time.Until(deadline)
+		select {
+		case <-resume:
+			deadline = time.Now().Add(remaining)
+		case <-p.stop:
+			return false
+		}
+	}
+}
+
+// message is the recorded message to publish, with its topic rewritten. The
+// topic alias and subscription identifier belonged to the recorded connection
+// and are dropped.
+func (p *Player) message(e msglog.Entry) client.Message {
+	msg := e.Message()
+	msg.Duplicate = false
+	msg.MessageId = 0
+	if p.opts.FromPrefix != "" || p.opts.ToPrefix != "" {
+		if rest, ok := strings.CutPrefix(msg.Topic, p.opts.FromPrefix); ok {
+			msg.Topic = p.opts.ToPrefix + rest
+		}
+	}
+	if msg.Properties != nil {
+		props := *msg.Properties
+		props.TopicAlias = nil
+		props.SubscriptionIdentifier = nil
+		msg.Properties = &props
+	}
+	return msg
+}
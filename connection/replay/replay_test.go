@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+)
+
+// recorder collects what a player publishes and when
+type recorder struct {
+	mu        sync.Mutex
+	start     time.Time
+	topics    []string
+	offsets   []time.Duration // since start
+	published chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{start: time.Now(), published: make(chan struct{}, 100)}
+}
+
+func (r *recorder) publish(msg client.Message) {
+	r.mu.Lock()
+	r.topics = append(r.topics, msg.Topic)
+	r.offsets = append(r.offsets, time.Since(r.start))
+	r.mu.Unlock()
+	r.published <- struct{}{}
+}
+
+func (r *recorder) await(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-r.published:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a publish")
+		}
+	}
+}
+
+// entries are received gap apart on the given topics
+func entries(gap time.Duration, topics ...string) []msglog.Entry {
+	at := time.Now()
+	e := make([]msglog.Entry, len(topics))
+	for i, topic := range topics {
+		e[i] = msglog.Entry{At: at.Add(time.Duration(i) * gap), Topic: topic, Payload: []byte("x")}
+	}
+	return e
+}
+
+func TestPlayerTiming(t *testing.T) {
+	const gap = 100 * time.Millisecond
+	tests := []struct {
+		name  string
+		speed float64
+		want  []time.Duration // publish offsets
+	}{
+		{"recorded speed", 1, []time.Duration{0, gap, 2 * gap}},
+		{"zero is recorded speed", 0, []time.Duration{0, gap, 2 * gap}},
+		{"twice as fast", 2, []time.Duration{0, gap / 2, gap}},
+		{"half as fast", 0.5, []time.Duration{0, 2 * gap, 4 * gap}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := newRecorder()
+			p := New(entries(gap, "a", "b", "c"), r.publish, Options{Speed: tt.speed})
+			p.Start()
+			defer p.Stop()
+			r.await(t, len(tt.want))
+
+			for i, want := range tt.want {
+				// timers may fire late but never early
+				if got := r.offsets[i]; got < want || got > want+gap/2 {
+					t.Errorf("message %d published after %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlayerPause(t *testing.T) {
+	const gap = 200 * time.Millisecond
+	const pause = 300 * time.Millisecond
+	r := newRecorder()
+	p := New(entries(gap, "a", "b"), r.publish, Options{})
+	p.Start()
+	defer p.Stop()
+	r.await(t, 1)
+
+	time.Sleep(gap / 2)
+	p.TogglePause()
+	if !p.Status().Paused {
+		t.Fatal("not paused")
+	}
+	time.Sleep(pause)
+	if n := len(r.published); n != 0 {
+		t.Fatalf("%d messages published while paused", n)
+	}
+	p.TogglePause()
+	r.await(t, 1)
+
+	// the second message keeps the rest of its delay after the pause
+	want := gap + pause
+	if got := r.offsets[1]; got < want || got > want+gap/2 {
+		t.Errorf("second message published after %v, want %v", got, want)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if s := p.Status(); !s.Done || s.Position != 2 || s.Paused {
+		t.Errorf("status %+v after the last message, want done at position 2", s)
+	}
+}
+
+func TestPlayerLoopAndStop(t *testing.T) {
+	r := newRecorder()
+	p := New(entries(time.Millisecond, "a", "b"), r.publish, Options{Loop: true, Speed: 10})
+	p.Start()
+	r.await(t, 5)
+	p.Stop()
+
+	if s := p.Status(); s.Pass < 3 {
+		t.Errorf("pass %d after 5 messages of 2, want at least 3", s.Pass)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, topic := range r.topics[:5] {
+		if want := []string{"a", "b"}[i%2]; topic != want {
+			t.Errorf("message %d on %s, want %s", i, topic, want)
+		}
+	}
+}
+
+func TestPlayerMessage(t *testing.T) {
+	alias, subId := uint16(3), 7
+	tests := []struct {
+		name  string
+		opts  Options
+		topic string
+		want  string
+	}{
+		{"no rewrite", Options{}, "site1/temp", "site1/temp"},
+		{"prefix replaced", Options{FromPrefix: "site1/", ToPrefix: "test/site1/"}, "site1/temp", "test/site1/temp"},
+		{"other prefix kept", Options{FromPrefix: "site1/", ToPrefix: "test/"}, "site2/temp", "site2/temp"},
+		{"prefix added", Options{ToPrefix: "test/"}, "site1/temp", "test/site1/temp"},
+		{"prefix removed", Options{FromPrefix: "site1/"}, "site1/temp", "temp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(nil, nil, tt.opts)
+			msg := p.message(msglog.Entry{
+				Topic:      tt.topic,
+				Duplicate:  true,
+				MessageId:  12,
+				Properties: &client.Properties{ContentType: "text/plain", TopicAlias: &alias, SubscriptionIdentifier: &subId},
+			})
+			if msg.Topic != tt.want {
+				t.Errorf("published on %s, want %s", msg.Topic, tt.want)
+			}
+			if msg.Duplicate || msg.MessageId != 0 {
+				t.Error("duplicate flag or message id of the recording kept")
+			}
+			if msg.Properties.TopicAlias != nil || msg.Properties.SubscriptionIdentifier != nil || msg.Properties.ContentType != "text/plain" {
+				t.Errorf("properties %+v, want only the ones of the recorded connection dropped", msg.Properties)
+			}
+		})
+	}
+}
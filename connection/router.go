@@ -0,0 +1,208 @@
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/OmegaRelay/mqtt-tui/connection/topictree"
+	"github.com/OmegaRelay/mqtt-tui/program"
+)
+
+// router receives every message of the connection and hands it to each
+// subscription whose topic filter matches, so overlapping filters all get
+// the message. Every message is also added to the topic tree and observed
+// by the decoder session before it is routed, and appended to the message
+// log while it is open and to the session being recorded.
+//
+// Brokers may send a message once for every subscription of the client it
+// matches, including the one of the topic tree. Where the copies can be told
+// apart only the first of them is routed, see isCopy.
+type router struct {
+	mu         sync.RWMutex
+	subs       []subscription.Model
+	treeFilter string // subscribed to while the topic tree is open, empty otherwise
+	recorder   *msglog.Log
+	tree       *topictree.Tree
+	session    *subscription.Session
+	log        *msglog.Log
+
+	// the last message routed and the copies of it received so far
+	last       *client.Message
+	copies     int
+	copyFilter []string
+}
+
+func (r *router) setSubscriptions(subs []subscription.Model) {
+	r.mu.Lock()
+	r.subs = subs
+	r.mu.Unlock()
+}
+
+func (r *router) setTreeFilter(filter string) {
+	r.mu.Lock()
+	r.treeFilter = filter
+	r.mu.Unlock()
+}
+
+func (r *router) setRecorder(recorder *msglog.Log) {
+	r.mu.Lock()
+	r.recorder = recorder
+	r.mu.Unlock()
+}
+
+func (r *router) route(msg client.Message) {
+	r.mu.Lock()
+	subs, recorder := r.subs, r.recorder
+	isCopy := r.isCopy(msg)
+	r.mu.Unlock()
+	if isCopy {
+		return
+	}
+
+	at := time.Now()
+	err := r.log.Append(msglog.NewEntry(msg, at))
+	if err != nil {
+		// stop logging rather than report every following message
+		r.log.Close()
+		go program.SendErrorMsg(fmt.Errorf("message log closed: %w", err))
+	}
+	if recorder != nil {
+		err = recorder.Append(msglog.NewEntry(msg, at))
+		if err != nil {
+			recorder.Close()
+			go program.SendErrorMsg(fmt.Errorf("recording stopped: %w", err))
+		}
+	}
+
+	obs := r.session.Observe(msg, at)
+	r.tree.Add(msg, at)
+	for _, sub := range subs {
+		filter := sub.Data().Topic
+		// retained messages are sent again for each new subscription, they
+		// only belong to the one they were sent for
+		if msg.Retained && msg.Filter != "" && filter != msg.Filter {
+			continue
+		}
+		if client.MatchTopic(filter, msg.Topic) {
+			sub.OnPubHandler(msg, obs)
+		}
+	}
+}
+
+// isCopy reports whether msg is another copy of the last message, sent for a
+// further subscription it matches. The copies of a message follow each other
+// and are told apart by the filter they were sent for, which is only known
+// with MQTT v5 subscription identifiers. Without it every message is routed,
+// as a broker may as well send a single copy and identical messages in a row
+// must not be lost. Retained messages are not copies, they are sent again
+// whenever a filter is subscribed to.
+func (r *router) isCopy(msg client.Message) bool {
+	filters := r.matchingFilters(msg.Topic)
+	if msg.Retained || msg.Filter == "" || filters < 2 {
+		r.last = nil
+		return false
+	}
+
+	last := r.last
+	isCopy := last != nil && r.copies < filters && last.Topic == msg.Topic &&
+		bytes.Equal(last.Payload, msg.Payload) && !slices.Contains(r.copyFilter, msg.Filter)
+	if isCopy {
+		r.copies++
+		r.copyFilter = append(r.copyFilter, msg.Filter)
+		return true
+	}
+
+	r.last = &msg
+	r.copies = 1
+	r.copyFilter = []string{msg.Filter}
+	return false
+}
+
+// matchingFilters returns the number of subscribed filters matching topic,
+// subscriptions with the same filter share one at the broker
+func (r *router) matchingFilters(topic string) int {
+	filters := make(map[string]bool, len(r.subs)+1)
+	if r.treeFilter != "" && client.MatchTopic(r.treeFilter, topic) {
+		filters[r.treeFilter] = true
+	}
+	for _, sub := range r.subs {
+		if filter := sub.Data().Topic; client.MatchTopic(filter, topic) {
+			filters[filter] = true
+		}
+	}
+	return len(filters)
+}
+
+// restore hands messages loaded from the message log to the subscriptions
+// without notifying the program, the topic tree only shows live traffic
+func (r *router) restore(entries []msglog.Entry) {
+	r.mu.RLock()
+	subs := r.subs
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		msg := e.Message()
+		obs := r.session.Observe(msg, e.At)
+		for _, sub := range subs {
+			if client.MatchTopic(sub.Data().Topic, msg.Topic) {
+				sub.Restore(msg, e.At, obs)
+			}
+		}
+	}
+}
+
+// syncRouter updates the router after the subscriptions list has changed
+func (m Model) syncRouter() {
+	m.router.setSubscriptions(m.subscriptionModels())
+}
+
+func (m Model) subscriptionModels() []subscription.Model {
+	subs := make([]subscription.Model, 0, len(m.subscriptions.Items()))
+	for _, item := range m.subscriptions.Items() {
+		if sub, ok := item.(subscription.Model); ok {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func (m Model) treeFilter() string {
+	if m.data.TreeFilter == "" {
+		return DefaultTreeFilter
+	}
+	return m.data.TreeFilter
+}
+
+// subscribe subscribes to filter at the highest QoS of the subscriptions
+// using it, if any still do
+func (m Model) subscribe(filter string) {
+	qos, used := byte(0), m.treeOpen && filter == m.treeFilter()
+	for _, sub := range m.subscriptionModels() {
+		if sub.Data().Topic == filter {
+			qos, used = max(qos, sub.Data().Qos), true
+		}
+	}
+	if !used {
+		return
+	}
+	go handleTokenErr(m.client.Subscribe(filter, qos))
+}
+
+// unsubscribe unsubscribes from filter once no subscription uses it anymore
+func (m Model) unsubscribe(filter string) {
+	if m.treeOpen && filter == m.treeFilter() {
+		return
+	}
+	for _, sub := range m.subscriptionModels() {
+		if sub.Data().Topic == filter {
+			return
+		}
+	}
+	go handleTokenErr(m.client.Unsubscribe(filter))
+}
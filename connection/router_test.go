@@ -0,0 +1,81 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+)
+
+func TestRouterIsCopy(t *testing.T) {
+	msg := func(payload string, filter string) client.Message {
+		return client.Message{Topic: "a/b", Payload: []byte(payload), Filter: filter}
+	}
+	retained := msg("1", "#")
+	retained.Retained = true
+
+	tests := []struct {
+		name       string
+		filters    []string // of the subscriptions
+		treeFilter string
+		received   []client.Message
+		want       []bool // whether each received message is a copy
+	}{
+		{
+			"single filter",
+			[]string{"a/b"}, "",
+			[]client.Message{msg("1", ""), msg("1", "")},
+			[]bool{false, false},
+		},
+		{
+			"identical filters share a subscription",
+			[]string{"a/b", "a/b"}, "",
+			[]client.Message{msg("1", ""), msg("1", "")},
+			[]bool{false, false},
+		},
+		{
+			"without filters identical messages are routed",
+			[]string{"a/b"}, "#",
+			[]client.Message{msg("1", ""), msg("1", ""), msg("1", ""), msg("1", "")},
+			[]bool{false, false, false, false},
+		},
+		{
+			"without filters different payloads",
+			[]string{"a/+"}, "#",
+			[]client.Message{msg("1", ""), msg("2", ""), msg("2", "")},
+			[]bool{false, false, false},
+		},
+		{
+			"v5 one copy per subscription",
+			[]string{"a/+", "+/b"}, "#",
+			[]client.Message{msg("1", "a/+"), msg("1", "#"), msg("1", "+/b"), msg("1", "#"), msg("1", "a/+")},
+			[]bool{false, true, true, false, true},
+		},
+		{
+			"v5 broker sending a single copy",
+			[]string{"a/+"}, "#",
+			[]client.Message{msg("1", "a/+"), msg("1", "a/+"), msg("1", "a/+")},
+			[]bool{false, false, false},
+		},
+		{
+			"retained messages",
+			[]string{"a/+"}, "#",
+			[]client.Message{retained, retained, msg("1", "#"), retained},
+			[]bool{false, false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &router{treeFilter: tt.treeFilter}
+			for _, filter := range tt.filters {
+				r.subs = append(r.subs, subscription.NewModel(subscription.Data{Topic: filter}))
+			}
+			for i, msg := range tt.received {
+				if got := r.isCopy(msg); got != tt.want[i] {
+					t.Errorf("message %d is a copy: %t, want %t", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package scroll
+
+// Offset returns the offset of the first visible row so that idx is visible
+// in a window of rows, moving the window as little as possible
+func Offset(idx int, offset int, rows int) int {
+	if idx < offset {
+		return idx
+	}
+	if idx >= offset+rows {
+		return idx - rows + 1
+	}
+	return offset
+}
@@ -0,0 +1,28 @@
+package scroll
+
+import "testing"
+
+func TestOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		idx    int
+		offset int
+		rows   int
+		want   int
+	}{
+		{"visible at the top", 0, 0, 5, 0},
+		{"visible at the bottom", 4, 0, 5, 0},
+		{"below the window", 5, 0, 5, 1},
+		{"far below the window", 12, 2, 5, 8},
+		{"above the window", 3, 6, 5, 3},
+		{"single row", 7, 3, 1, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Offset(tt.idx, tt.offset, tt.rows); got != tt.want {
+				t.Errorf("Offset(%d, %d, %d) = %d, want %d", tt.idx, tt.offset, tt.rows, got, tt.want)
+			}
+		})
+	}
+}
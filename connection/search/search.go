@@ -0,0 +1,216 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Query is a parsed search, all of its terms have to match. Terms are
+//
+//	word, "some words"     payload contains the text
+//	/regex/                payload matches the regular expression
+//	topic:text, topic:/re/ topic contains the text or matches
+//	payload:text           same as a bare word
+//	since:10m, since:15:04 received after a duration ago or a time
+//	until:...              received before
+//
+// Text is matched case insensitively unless it contains upper case letters.
+type Query struct {
+	raw     string
+	topic   []*regexp.Regexp
+	payload []*regexp.Regexp
+	since   *bound
+	until   *bound
+}
+
+// bound is a relative or absolute point in time, relative bounds move with
+// the clock so that a filter keeps working while messages stream in
+type bound struct {
+	ago time.Duration
+	at  time.Time
+}
+
+func (b bound) time(now time.Time) time.Time {
+	if b.ago > 0 {
+		return now.Add(-b.ago)
+	}
+	return b.at
+}
+
+func Parse(s string, now time.Time) (Query, error) {
+	q := Query{raw: strings.TrimSpace(s)}
+	tokens, err := tokenize(q.raw)
+	if err != nil {
+		return Query{}, err
+	}
+	for _, token := range tokens {
+		field, value, hasField := strings.Cut(token, ":")
+		if !hasField || strings.HasPrefix(token, "/") || strings.HasPrefix(token, `"`) {
+			field, value = "payload", token
+		}
+		switch field {
+		case "topic", "payload":
+			re, err := compile(value)
+			if err != nil {
+				return Query{}, err
+			}
+			if field == "topic" {
+				q.topic = append(q.topic, re)
+			} else {
+				q.payload = append(q.payload, re)
+			}
+		case "since", "until":
+			b, err := parseBound(value, now)
+			if err != nil {
+				return Query{}, fmt.Errorf("invalid %s: %w", field, err)
+			}
+			if field == "since" {
+				q.since = &b
+			} else {
+				q.until = &b
+			}
+		default:
+			// a word which happens to contain a colon, e.g. a time of day
+			re, err := compile(token)
+			if err != nil {
+				return Query{}, err
+			}
+			q.payload = append(q.payload, re)
+		}
+	}
+	return q, nil
+}
+
+// tokenize splits s at spaces outside of quotes and slashes
+func tokenize(s string) ([]string, error) {
+	tokens := make([]string, 0)
+	var b strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote == '/':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '/') && (b.Len() == 0 || strings.HasSuffix(b.String(), ":")):
+			quote = r
+		case quote == 0 && r == ' ':
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c", quote)
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens, nil
+}
+
+// compile turns a text, "quoted text" or /regex/ into a regular expression
+func compile(value string) (*regexp.Regexp, error) {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile(value[1 : len(value)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %w", value, err)
+		}
+		return re, nil
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	if value == "" {
+		return nil, errors.New("empty search term")
+	}
+	expr := regexp.QuoteMeta(value)
+	if strings.ToLower(value) == value {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr), nil
+}
+
+func parseBound(value string, now time.Time) (bound, error) {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return bound{ago: d}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return bound{at: t}, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			// a time of day is today
+			y, m, d := now.Date()
+			return bound{at: time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location())}, nil
+		}
+	}
+	return bound{}, fmt.Errorf("%q is neither a duration like 10m nor a time like 15:04", value)
+}
+
+func (q Query) String() string {
+	return q.raw
+}
+
+func (q Query) IsEmpty() bool {
+	return q.raw == ""
+}
+
+// MatchText reports whether the topic and payload terms match, it does not
+// depend on the time so the result can be cached
+func (q Query) MatchText(topic string, payload string) bool {
+	for _, re := range q.topic {
+		if !re.MatchString(topic) {
+			return false
+		}
+	}
+	for _, re := range q.payload {
+		if !re.MatchString(payload) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTime reports whether at is within the time range of the query
+func (q Query) MatchTime(at time.Time, now time.Time) bool {
+	if q.since != nil && at.Before(q.since.time(now)) {
+		return false
+	}
+	if q.until != nil && at.After(q.until.time(now)) {
+		return false
+	}
+	return true
+}
+
+// TopicPattern and PayloadPattern combine the terms for highlighting, nil if
+// there are none
+func (q Query) TopicPattern() *regexp.Regexp {
+	return combine(q.topic)
+}
+
+func (q Query) PayloadPattern() *regexp.Regexp {
+	return combine(q.payload)
+}
+
+func combine(res []*regexp.Regexp) *regexp.Regexp {
+	if len(res) == 0 {
+		return nil
+	}
+	parts := make([]string, len(res))
+	for i, re := range res {
+		parts[i] = "(?:" + re.String() + ")"
+	}
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}
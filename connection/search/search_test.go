@@ -0,0 +1,135 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string // part of the error, empty if it parses
+	}{
+		{"", ""},
+		{"  temp  ", ""},
+		{`"two words" topic:/^a\/b$/ since:10m until:2026-01-02`, ""},
+		{"10:30", ""},
+		{`"unclosed`, `missing closing "`},
+		{"/unclosed", "missing closing /"},
+		{`topic:"unclosed`, `missing closing "`},
+		{`""`, "empty search term"},
+		{"topic:", "empty search term"},
+		{"/a(/", "invalid regular expression /a(/"},
+		{"topic:/[/", "invalid regular expression"},
+		{"since:soon", "invalid since"},
+		{"until:-5m", "invalid until"},
+		{"since:0s", "invalid since"},
+		{"since:25:00", "invalid since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query, now)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != strings.TrimSpace(tt.query) {
+				t.Errorf("query %q, want %q", q.String(), strings.TrimSpace(tt.query))
+			}
+		})
+	}
+}
+
+func TestMatchText(t *testing.T) {
+	tests := []struct {
+		query   string
+		topic   string
+		payload string
+		want    bool
+	}{
+		{"", "a/b", "anything", true},
+		{"temp", "a/b", `{"Temp":21}`, true},
+		{"Temp", "a/b", `{"temp":21}`, false},
+		{"Temp", "a/b", `{"Temp":21}`, true},
+		{"temp 21", "a/b", `{"temp":21}`, true},
+		{"temp 22", "a/b", `{"temp":21}`, false},
+		{`"temp":21`, "a/b", `{"temp":21}`, true},
+		{`"temp 21"`, "a/b", `temp 21`, true},
+		{`"temp 21"`, "a/b", `temp: 21`, false},
+		{"payload:21", "a/b", `{"temp":21}`, true},
+		{"a.b", "a/b", "axb", false},
+		{"/a.b/", "a/b", "axb", true},
+		{`/^\d+$/`, "a/b", "123", true},
+		{`/^\d+$/`, "a/b", "12a", false},
+		{"topic:sensors", "sensors/1/temp", "21", true},
+		{"topic:sensors", "devices/1", "sensors", false},
+		{`topic:/^sensors\/\d+\/temp$/`, "sensors/12/temp", "", true},
+		{`topic:/^sensors\/\d+\/temp$/`, "sensors/x/temp", "", false},
+		{`topic:"sensors/1"`, "sensors/1/temp", "", true},
+		{"10:30", "a/b", "at 10:30", true},
+		{"since:10m", "a/b", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.MatchText(tt.topic, tt.payload); got != tt.want {
+				t.Errorf("MatchText(%q, %q) = %t, want %t", tt.topic, tt.payload, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		at    time.Time
+		now   time.Time // at matching, defaults to the time of parsing
+		want  bool
+	}{
+		{"no bounds", "temp", now.Add(-time.Hour), time.Time{}, true},
+		{"since duration", "since:10m", now.Add(-5 * time.Minute), time.Time{}, true},
+		{"before since duration", "since:10m", now.Add(-15 * time.Minute), time.Time{}, false},
+		{"since duration moves with now", "since:10m", now.Add(-5 * time.Minute), now.Add(10 * time.Minute), false},
+		{"until duration", "until:10m", now.Add(-15 * time.Minute), time.Time{}, true},
+		{"after until duration", "until:10m", now.Add(-5 * time.Minute), time.Time{}, false},
+		{"since time of day", "since:11:30", now.Add(-20 * time.Minute), time.Time{}, true},
+		{"before since time of day", "since:11:30", now.Add(-40 * time.Minute), time.Time{}, false},
+		{"since time of day stays", "since:11:30", now.Add(-20 * time.Minute), now.Add(24 * time.Hour), true},
+		{"since date", "since:2026-01-02", now.Add(-time.Hour), time.Time{}, true},
+		{"before since date", "since:2026-01-02", now.Add(-13 * time.Hour), time.Time{}, false},
+		{"until RFC 3339", "until:2026-01-02T11:00:00Z", now.Add(-2 * time.Hour), time.Time{}, true},
+		{"after until RFC 3339", "until:2026-01-02T11:00:00Z", now, time.Time{}, false},
+		{"within range", "since:1h until:10m", now.Add(-30 * time.Minute), time.Time{}, true},
+		{"outside range", "since:1h until:10m", now.Add(-2 * time.Hour), time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matchNow := tt.now
+			if matchNow.IsZero() {
+				matchNow = now
+			}
+			if got := q.MatchTime(tt.at, matchNow); got != tt.want {
+				t.Errorf("MatchTime(%s) = %t, want %t", tt.at.Format(time.TimeOnly), got, tt.want)
+			}
+		})
+	}
+}
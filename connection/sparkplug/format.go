@@ -0,0 +1,210 @@
+package sparkplug
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+const timeFormat = "2006-01-02 15:04:05.000"
+
+// Render decodes a Sparkplug B payload received on topic into a readable
+// listing of its metrics. Aliases are resolved with aliases, the ones known
+// when the message was received, and the state of the edge node is taken from
+// tracker. Both may be nil.
+func Render(topic string, payload []byte, tracker *Tracker, aliases Aliases) (string, error) {
+	st, isSparkplug := ParseTopic(topic)
+	if isSparkplug && st.Type == STATE {
+		// host application state is JSON or, before Sparkplug 3.0, text
+		return fmt.Sprintf("primary host %s state\n\n%s", st.Host, payload), nil
+	}
+
+	p, err := DecodePayload(payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid Sparkplug B payload: %w", err)
+	}
+
+	b := &strings.Builder{}
+	if isSparkplug {
+		renderTopic(b, st, tracker)
+	}
+	if p.Timestamp != nil {
+		fmt.Fprintf(b, "timestamp: %s\n", formatMillis(*p.Timestamp))
+	}
+	if p.Seq != nil {
+		fmt.Fprintf(b, "seq:       %d\n", *p.Seq)
+	}
+	if p.Uuid != "" {
+		fmt.Fprintf(b, "uuid:      %s\n", p.Uuid)
+	}
+	if len(p.Body) > 0 {
+		fmt.Fprintf(b, "body:      %d bytes\n", len(p.Body))
+	}
+
+	fmt.Fprintf(b, "\n%d metrics\n", len(p.Metrics))
+	resolve := func(alias uint64) (string, bool) {
+		name, ok := aliases[alias]
+		return name, ok
+	}
+	renderMetrics(b, p.Metrics, resolve, "  ")
+	return b.String(), nil
+}
+
+func renderTopic(b *strings.Builder, st Topic, tracker *Tracker) {
+	fmt.Fprintf(b, "%s %s\n", Namespace, st.Type)
+	fmt.Fprintf(b, "group:     %s\n", st.Group)
+
+	state, known := NodeState{}, false
+	if tracker != nil {
+		state, known = tracker.Node(st.Group, st.Edge)
+	}
+	edge := st.Edge
+	if known {
+		edge += " (" + lifecycle(state.Online, state.Since)
+		if state.BdSeq != nil {
+			edge += fmt.Sprintf(", bdSeq %d", *state.BdSeq)
+		}
+		edge += ")"
+	} else {
+		edge += " (no birth seen)"
+	}
+	fmt.Fprintf(b, "edge node: %s\n", edge)
+
+	if st.IsDevice() {
+		device := st.Device + " (no birth seen)"
+		if ds, ok := state.Devices[st.Device]; ok {
+			device = st.Device + " (" + lifecycle(ds.Online, ds.Since) + ")"
+		}
+		fmt.Fprintf(b, "device:    %s\n", device)
+	} else if len(state.Devices) > 0 {
+		online := 0
+		for _, ds := range state.Devices {
+			if ds.Online {
+				online++
+			}
+		}
+		fmt.Fprintf(b, "devices:   %d of %d online\n", online, len(state.Devices))
+	}
+}
+
+func lifecycle(online bool, since time.Time) string {
+	if online {
+		return "online since " + since.Format(timeFormat)
+	}
+	return "offline since " + since.Format(timeFormat)
+}
+
+func formatMillis(ms uint64) string {
+	return time.UnixMilli(int64(ms)).Local().Format(timeFormat)
+}
+
+func renderMetrics(b *strings.Builder, metrics []Metric, resolve func(uint64) (string, bool), indent string) {
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	for _, m := range metrics {
+		name := m.Name
+		alias := ""
+		if m.Alias != nil {
+			alias = fmt.Sprintf("alias %d", *m.Alias)
+			if name == "" {
+				if resolved, ok := resolve(*m.Alias); ok {
+					name = resolved
+				} else {
+					name = "<unknown alias>"
+				}
+			}
+		}
+
+		value := formatValue(m.Value)
+		if m.IsNull {
+			value = "null"
+		}
+		switch m.Value.(type) {
+		case DataSet, Template:
+			value = ""
+		}
+
+		timestamp := ""
+		if m.Timestamp != nil {
+			timestamp = formatMillis(*m.Timestamp)
+		}
+		var flags []string
+		if m.IsHistorical {
+			flags = append(flags, "historical")
+		}
+		if m.IsTransient {
+			flags = append(flags, "transient")
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\n", indent, name, alias, m.Datatype, value, timestamp, strings.Join(flags, ","))
+
+		switch v := m.Value.(type) {
+		case DataSet, Template:
+			// written below the metric once the columns are aligned
+			w.Flush()
+			renderNested(b, v, resolve, indent+"  ")
+		}
+	}
+	w.Flush()
+}
+
+func renderNested(b *strings.Builder, value any, resolve func(uint64) (string, bool), indent string) {
+	switch v := value.(type) {
+	case DataSet:
+		w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s%s\n", indent, strings.Join(v.Columns, "\t"))
+		types := make([]string, len(v.Types))
+		for i, t := range v.Types {
+			types[i] = t.String()
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, strings.Join(types, "\t"))
+		for _, row := range v.Rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = formatValue(cell)
+			}
+			fmt.Fprintf(w, "%s%s\n", indent, strings.Join(cells, "\t"))
+		}
+		w.Flush()
+	case Template:
+		header := "template"
+		if v.IsDefinition {
+			header = "template definition"
+		}
+		if v.TemplateRef != "" {
+			header += " of " + v.TemplateRef
+		}
+		if v.Version != "" {
+			header += " version " + v.Version
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, header)
+		// template members do not use the aliases of the edge node
+		renderMetrics(b, v.Metrics, func(uint64) (string, bool) { return "", false }, indent+"  ")
+	}
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if !utf8.ValidString(v) {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case []byte:
+		if len(v) > 16 {
+			return fmt.Sprintf("%d bytes % x …", len(v), v[:16])
+		}
+		return fmt.Sprintf("%d bytes % x", len(v), v)
+	case time.Time:
+		return v.Local().Format(timeFormat)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatValue(item)
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	return fmt.Sprint(value)
+}
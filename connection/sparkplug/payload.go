@@ -0,0 +1,404 @@
+package sparkplug
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Payload is a decoded Sparkplug B payload, only the fields shown by the
+// message pane are kept
+type Payload struct {
+	Timestamp *uint64 // ms since the epoch
+	Metrics   []Metric
+	Seq       *uint64
+	Uuid      string
+	Body      []byte
+}
+
+type Metric struct {
+	Name         string
+	Alias        *uint64
+	Timestamp    *uint64
+	Datatype     Datatype
+	IsHistorical bool
+	IsTransient  bool
+	IsNull       bool
+	Value        any // nil if the metric has no value
+}
+
+type DataSet struct {
+	Columns []string
+	Types   []Datatype
+	Rows    [][]any
+}
+
+type Template struct {
+	Version      string
+	Metrics      []Metric
+	TemplateRef  string
+	IsDefinition bool
+}
+
+type Datatype uint32
+
+var datatypeNames = []string{
+	"Unknown", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32", "UInt64",
+	"Float", "Double", "Boolean", "String", "DateTime", "Text", "UUID", "DataSet", "Bytes",
+	"File", "Template", "PropertySet", "PropertySetList", "Int8Array", "Int16Array",
+	"Int32Array", "Int64Array", "UInt8Array", "UInt16Array", "UInt32Array", "UInt64Array",
+	"FloatArray", "DoubleArray", "BooleanArray", "StringArray", "DateTimeArray",
+}
+
+const (
+	Int8 Datatype = iota + 1
+	Int16
+	Int32
+	Int64
+	UInt8
+	UInt16
+	UInt32
+	UInt64
+	Float
+	Double
+	Boolean
+	String
+	DateTime
+	Text
+	UUID
+	DataSetType
+	Bytes
+	File
+	TemplateType
+	PropertySet
+	PropertySetList
+	Int8Array
+	Int16Array
+	Int32Array
+	Int64Array
+	UInt8Array
+	UInt16Array
+	UInt32Array
+	UInt64Array
+	FloatArray
+	DoubleArray
+	BooleanArray
+	StringArray
+	DateTimeArray
+)
+
+func (d Datatype) String() string {
+	if int(d) < len(datatypeNames) {
+		return datatypeNames[d]
+	}
+	return fmt.Sprintf("Datatype(%d)", d)
+}
+
+var errTruncated = errors.New("truncated field")
+
+// fields calls fn for every field of a protobuf message, value holds the
+// varint, fixed or length delimited value depending on the wire type
+func fields(b []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, data); err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// packed returns the values of a repeated varint field, which may or may not
+// be packed
+func packed(typ protowire.Type, v uint64, data []byte) ([]uint64, error) {
+	if typ != protowire.BytesType {
+		return []uint64{v}, nil
+	}
+	values := make([]uint64, 0)
+	for len(data) > 0 {
+		v, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		values = append(values, v)
+		data = data[n:]
+	}
+	return values, nil
+}
+
+func DecodePayload(b []byte) (Payload, error) {
+	p := Payload{}
+	err := fields(b, func(num protowire.Number, _ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			p.Timestamp = &v
+		case 2:
+			m, err := decodeMetric(data)
+			if err != nil {
+				return err
+			}
+			p.Metrics = append(p.Metrics, m)
+		case 3:
+			p.Seq = &v
+		case 4:
+			p.Uuid = string(data)
+		case 5:
+			p.Body = data
+		}
+		return nil
+	})
+	return p, err
+}
+
+func decodeMetric(b []byte) (Metric, error) {
+	m := Metric{}
+	var raw struct {
+		v     uint64
+		data  []byte
+		field protowire.Number
+	}
+	err := fields(b, func(num protowire.Number, _ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			m.Name = string(data)
+		case 2:
+			m.Alias = &v
+		case 3:
+			m.Timestamp = &v
+		case 4:
+			m.Datatype = Datatype(v)
+		case 5:
+			m.IsHistorical = v != 0
+		case 6:
+			m.IsTransient = v != 0
+		case 7:
+			m.IsNull = v != 0
+		case 10, 11, 12, 13, 14, 15, 16, 17, 18, 19:
+			raw.field, raw.v, raw.data = num, v, data
+		}
+		return nil
+	})
+	if err != nil || raw.field == 0 || m.IsNull {
+		return m, err
+	}
+
+	m.Value, err = metricValue(m.Datatype, raw.field, raw.v, raw.data)
+	return m, err
+}
+
+// metricValue converts the value field of a metric according to its
+// datatype, signed integers are sent as two's complement in the unsigned
+// fields
+func metricValue(datatype Datatype, field protowire.Number, v uint64, data []byte) (any, error) {
+	switch field {
+	case 10: // int_value
+		switch datatype {
+		case Int8:
+			return int8(v), nil
+		case Int16:
+			return int16(v), nil
+		case Int32:
+			return int32(v), nil
+		}
+		return uint32(v), nil
+	case 11: // long_value
+		switch datatype {
+		case Int64:
+			return int64(v), nil
+		case DateTime:
+			return time.UnixMilli(int64(v)).UTC(), nil
+		}
+		return v, nil
+	case 12:
+		return math.Float32frombits(uint32(v)), nil
+	case 13:
+		return math.Float64frombits(v), nil
+	case 14:
+		return v != 0, nil
+	case 15:
+		return string(data), nil
+	case 16:
+		if datatype >= Int8Array && datatype <= DateTimeArray {
+			return decodeArray(datatype, data)
+		}
+		return data, nil
+	case 17:
+		return decodeDataSet(data)
+	case 18:
+		return decodeTemplate(data)
+	}
+	// extension values can not be decoded without knowing the extension
+	return data, nil
+}
+
+func decodeDataSet(b []byte) (DataSet, error) {
+	ds := DataSet{}
+	err := fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 2:
+			ds.Columns = append(ds.Columns, string(data))
+		case 3:
+			types, err := packed(typ, v, data)
+			if err != nil {
+				return err
+			}
+			for _, t := range types {
+				ds.Types = append(ds.Types, Datatype(t))
+			}
+		case 4:
+			row, err := decodeRow(data, ds.Types)
+			if err != nil {
+				return err
+			}
+			ds.Rows = append(ds.Rows, row)
+		}
+		return nil
+	})
+	return ds, err
+}
+
+func decodeRow(b []byte, types []Datatype) ([]any, error) {
+	row := make([]any, 0)
+	err := fields(b, func(num protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+		if num != 1 {
+			return nil
+		}
+		var value any
+		err := fields(data, func(num protowire.Number, _ protowire.Type, v uint64, data []byte) error {
+			var datatype Datatype
+			if len(row) < len(types) {
+				datatype = types[len(row)]
+			}
+			var err error
+			// DataSetValue uses the same value fields as Metric, starting at 1
+			value, err = metricValue(datatype, num+9, v, data)
+			return err
+		})
+		row = append(row, value)
+		return err
+	})
+	return row, err
+}
+
+func decodeTemplate(b []byte) (Template, error) {
+	t := Template{}
+	err := fields(b, func(num protowire.Number, _ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			t.Version = string(data)
+		case 2:
+			m, err := decodeMetric(data)
+			if err != nil {
+				return err
+			}
+			t.Metrics = append(t.Metrics, m)
+		case 4:
+			t.TemplateRef = string(data)
+		case 5:
+			t.IsDefinition = v != 0
+		}
+		return nil
+	})
+	return t, err
+}
+
+// decodeArray decodes the little endian packed arrays which Sparkplug sends
+// in bytes_value
+func decodeArray(datatype Datatype, data []byte) (any, error) {
+	switch datatype {
+	case BooleanArray:
+		if len(data) < 4 {
+			return nil, errTruncated
+		}
+		count := binary.LittleEndian.Uint32(data)
+		bits := data[4:]
+		if uint64(len(bits))*8 < uint64(count) {
+			return nil, errTruncated
+		}
+		values := make([]bool, count)
+		for i := range values {
+			values[i] = bits[i/8]&(0x80>>(i%8)) != 0
+		}
+		return values, nil
+	case StringArray:
+		values := make([]string, 0)
+		for len(data) > 0 {
+			s, rest, _ := bytes.Cut(data, []byte{0})
+			values = append(values, string(s))
+			data = rest
+		}
+		return values, nil
+	}
+
+	size := map[Datatype]int{
+		Int8Array: 1, UInt8Array: 1, Int16Array: 2, UInt16Array: 2, Int32Array: 4, UInt32Array: 4,
+		FloatArray: 4, Int64Array: 8, UInt64Array: 8, DoubleArray: 8, DateTimeArray: 8,
+	}[datatype]
+	if len(data)%size != 0 {
+		return nil, errTruncated
+	}
+	values := make([]any, 0, len(data)/size)
+	for ; len(data) > 0; data = data[size:] {
+		var v uint64
+		switch size {
+		case 1:
+			v = uint64(data[0])
+		case 2:
+			v = uint64(binary.LittleEndian.Uint16(data))
+		case 4:
+			v = uint64(binary.LittleEndian.Uint32(data))
+		case 8:
+			v = binary.LittleEndian.Uint64(data)
+		}
+		switch datatype {
+		case Int8Array:
+			values = append(values, int8(v))
+		case Int16Array:
+			values = append(values, int16(v))
+		case Int32Array:
+			values = append(values, int32(v))
+		case Int64Array:
+			values = append(values, int64(v))
+		case FloatArray:
+			values = append(values, math.Float32frombits(uint32(v)))
+		case DoubleArray:
+			values = append(values, math.Float64frombits(v))
+		case DateTimeArray:
+			values = append(values, time.UnixMilli(int64(v)).UTC())
+		default:
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
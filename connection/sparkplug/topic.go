@@ -0,0 +1,60 @@
+package sparkplug
+
+import "strings"
+
+// Namespace is the first topic level of all Sparkplug B topics
+const Namespace = "spBv1.0"
+
+// Message types of the Sparkplug B topic namespace
+const (
+	NBIRTH = "NBIRTH"
+	NDEATH = "NDEATH"
+	DBIRTH = "DBIRTH"
+	DDEATH = "DDEATH"
+	NDATA  = "NDATA"
+	DDATA  = "DDATA"
+	NCMD   = "NCMD"
+	DCMD   = "DCMD"
+	STATE  = "STATE"
+)
+
+// Topic is a parsed spBv1.0/<group>/<type>/<edge>[/<device>] topic. For
+// STATE messages of primary host applications only Type and Host are set.
+type Topic struct {
+	Group  string
+	Type   string
+	Edge   string
+	Device string
+	Host   string
+}
+
+// ParseTopic parses a Sparkplug B topic, false if topic is not in the
+// Sparkplug namespace
+func ParseTopic(topic string) (Topic, bool) {
+	levels := strings.Split(topic, "/")
+	if len(levels) < 2 || levels[0] != Namespace {
+		return Topic{}, false
+	}
+	if levels[1] == STATE {
+		// spBv1.0/STATE/<host>, Sparkplug 2.2 used STATE/<host> outside of
+		// the namespace
+		if len(levels) != 3 {
+			return Topic{}, false
+		}
+		return Topic{Type: STATE, Host: levels[2]}, true
+	}
+
+	if len(levels) < 4 || len(levels) > 5 {
+		return Topic{}, false
+	}
+	t := Topic{Group: levels[1], Type: levels[2], Edge: levels[3]}
+	if len(levels) == 5 {
+		t.Device = levels[4]
+	}
+	return t, true
+}
+
+// IsDevice is true for message types addressed to a device of an edge node
+func (t Topic) IsDevice() bool {
+	return t.Device != ""
+}
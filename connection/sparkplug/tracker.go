@@ -0,0 +1,162 @@
+package sparkplug
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// Tracker follows the births and deaths of the edge nodes and devices seen on
+// a connection, remembering the metric aliases they announced so that data
+// messages which only carry aliases can be shown with metric names
+type Tracker struct {
+	mu    sync.RWMutex
+	nodes map[nodeKey]*node
+}
+
+type nodeKey struct {
+	group string
+	edge  string
+}
+
+type node struct {
+	state   NodeState
+	aliases Aliases
+}
+
+// Aliases maps the metric aliases announced by the births of an edge node and
+// its devices to metric names. A birth adds its aliases to a copy, so Aliases
+// returned by the Tracker keep the names known at that time.
+type Aliases map[uint64]string
+
+// NodeState is the birth/death state of an edge node
+type NodeState struct {
+	Online  bool
+	Since   time.Time // of the last birth or death
+	BdSeq   *uint64   // birth/death sequence number of the last birth
+	Devices map[string]DeviceState
+}
+
+type DeviceState struct {
+	Online bool
+	Since  time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{nodes: make(map[nodeKey]*node)}
+}
+
+// Observe updates the state with a message received on the connection,
+// messages outside of the Sparkplug namespace are ignored
+func (t *Tracker) Observe(topic string, payload []byte, at time.Time) {
+	st, ok := ParseTopic(topic)
+	if !ok {
+		return
+	}
+	switch st.Type {
+	case NBIRTH, NDEATH, DBIRTH, DDEATH:
+	default:
+		return
+	}
+	p, err := DecodePayload(payload)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := nodeKey{group: st.Group, edge: st.Edge}
+	n, ok := t.nodes[key]
+	if !ok {
+		n = &node{}
+		n.state.Devices = make(map[string]DeviceState)
+		t.nodes[key] = n
+	}
+
+	switch st.Type {
+	case NBIRTH:
+		// a new birth replaces everything known about the node, its devices
+		// are born again after it
+		n.state = NodeState{Online: true, Since: at, BdSeq: bdSeq(p), Devices: make(map[string]DeviceState)}
+		n.aliases = nil
+		n.addAliases(p.Metrics)
+	case NDEATH:
+		// a will message from an older session must not kill a newer one
+		if seq := bdSeq(p); seq != nil && n.state.BdSeq != nil && *seq != *n.state.BdSeq {
+			return
+		}
+		n.state.Online = false
+		n.state.Since = at
+		for device, state := range n.state.Devices {
+			if state.Online {
+				n.state.Devices[device] = DeviceState{Online: false, Since: at}
+			}
+		}
+	case DBIRTH:
+		n.state.Devices[st.Device] = DeviceState{Online: true, Since: at}
+		n.addAliases(p.Metrics)
+	case DDEATH:
+		n.state.Devices[st.Device] = DeviceState{Online: false, Since: at}
+	}
+}
+
+func (n *node) addAliases(metrics []Metric) {
+	aliases := maps.Clone(n.aliases)
+	if aliases == nil {
+		aliases = make(Aliases)
+	}
+	for _, m := range metrics {
+		if m.Alias != nil && m.Name != "" {
+			aliases[*m.Alias] = m.Name
+		}
+	}
+	n.aliases = aliases
+}
+
+func bdSeq(p Payload) *uint64 {
+	for _, m := range p.Metrics {
+		if m.Name != "bdSeq" {
+			continue
+		}
+		switch v := m.Value.(type) {
+		case uint64:
+			return &v
+		case int64:
+			seq := uint64(v)
+			return &seq
+		}
+	}
+	return nil
+}
+
+// Node returns the state of an edge node, false if no birth or death of it
+// was seen
+func (t *Tracker) Node(group string, edge string) (NodeState, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n, ok := t.nodes[nodeKey{group: group, edge: edge}]
+	if !ok {
+		return NodeState{}, false
+	}
+	state := n.state
+	state.Devices = maps.Clone(n.state.Devices)
+	return state, true
+}
+
+// Aliases returns the aliases currently known for the edge node a message
+// received on topic belongs to, nil outside of the Sparkplug namespace or if
+// no birth of the node was seen
+func (t *Tracker) Aliases(topic string) Aliases {
+	st, ok := ParseTopic(topic)
+	if !ok {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n, ok := t.nodes[nodeKey{group: st.Group, edge: st.Edge}]
+	if !ok {
+		return nil
+	}
+	return n.aliases
+}
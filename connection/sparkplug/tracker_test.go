@@ -0,0 +1,104 @@
+package sparkplug
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// metric is encoded with an alias and, if name is not empty, a name and an
+// int32 value
+func metric(name string, alias uint64, value uint64) []byte {
+	var b []byte
+	if name != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, name)
+	}
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, alias)
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(Int32))
+	b = protowire.AppendTag(b, 10, protowire.VarintType)
+	b = protowire.AppendVarint(b, value)
+	return b
+}
+
+func payload(metrics ...[]byte) []byte {
+	var b []byte
+	for _, m := range metrics {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	}
+	return b
+}
+
+func TestTrackerAliases(t *testing.T) {
+	const (
+		nbirth = "spBv1.0/plant/NBIRTH/edge1"
+		dbirth = "spBv1.0/plant/DBIRTH/edge1/dev1"
+		ndata  = "spBv1.0/plant/NDATA/edge1"
+		ddata  = "spBv1.0/plant/DDATA/edge1/dev1"
+	)
+	type received struct {
+		topic   string
+		payload []byte
+	}
+
+	tests := []struct {
+		name     string
+		received []received // before the data message
+		topic    string
+		want     string // metric name shown for alias 1
+	}{
+		{"no birth", nil, ndata, "<unknown alias>"},
+		{"node birth", []received{{nbirth, payload(metric("temp", 1, 0))}}, ndata, "temp"},
+		{"device birth", []received{
+			{nbirth, payload(metric("uptime", 2, 0))},
+			{dbirth, payload(metric("temp", 1, 0))},
+		}, ddata, "temp"},
+		{"other edge node", []received{{"spBv1.0/plant/NBIRTH/edge2", payload(metric("temp", 1, 0))}}, ndata, "<unknown alias>"},
+		{"rebirth drops aliases", []received{
+			{nbirth, payload(metric("temp", 1, 0))},
+			{nbirth, payload(metric("uptime", 2, 0))},
+		}, ndata, "<unknown alias>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker()
+			for _, r := range tt.received {
+				tracker.Observe(r.topic, r.payload, time.Now())
+			}
+			got, err := Render(tt.topic, payload(metric("", 1, 42)), tracker, tracker.Aliases(tt.topic))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got, "  "+tt.want+"  ") {
+				t.Errorf("alias 1 is not shown as %s:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAliasesKeptAfterRebirth(t *testing.T) {
+	const topic = "spBv1.0/plant/NDATA/edge1"
+	tracker := NewTracker()
+	tracker.Observe("spBv1.0/plant/NBIRTH/edge1", payload(metric("temp", 1, 0)), time.Now())
+	aliases := tracker.Aliases(topic)
+
+	// the edge node is born again with another metric on the same alias
+	tracker.Observe("spBv1.0/plant/NBIRTH/edge1", payload(metric("pressure", 1, 0)), time.Now())
+	tracker.Observe("spBv1.0/plant/DBIRTH/edge1/dev1", payload(metric("level", 2, 0)), time.Now())
+
+	if aliases[1] != "temp" || len(aliases) != 1 {
+		t.Errorf("aliases taken before the rebirth changed to %v", aliases)
+	}
+	if got := tracker.Aliases(topic); got[1] != "pressure" || got[2] != "level" {
+		t.Errorf("current aliases %v, want 1 pressure and 2 level", got)
+	}
+	if got := tracker.Aliases("plant/NDATA/edge1"); got != nil {
+		t.Errorf("aliases %v outside of the Sparkplug namespace", got)
+	}
+}
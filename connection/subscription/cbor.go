@@ -0,0 +1,169 @@
+package subscription
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func init() {
+	RegisterDecoder(cborDecoder{})
+}
+
+// cborDecoder renders CBOR payloads as a tree. Containers, tags and simple
+// values are walked here so that tags are kept rather than being converted
+// into Go values, scalars are decoded by the cbor package.
+type cborDecoder struct{}
+
+func (cborDecoder) Name() string { return "cbor" }
+
+func (cborDecoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	r := bytes.NewReader(payload)
+	v, err := readCbor(r, 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid CBOR: %w", err)
+	}
+	if r.Len() > 0 {
+		return "", fmt.Errorf("%d bytes after the CBOR item", r.Len())
+	}
+	return renderTree(v)
+}
+
+const (
+	kCborBreak      = 0xff
+	kCborIndefinite = 31
+)
+
+// readCborHead reads the initial byte of an item and its argument, info is
+// the additional information of the initial byte
+func readCborHead(r *bytes.Reader) (major byte, info byte, arg uint64, err error) {
+	initial, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	major, info = initial>>5, initial&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == kCborIndefinite:
+		return major, info, 0, nil
+	case info > 27:
+		return 0, 0, 0, fmt.Errorf("invalid additional information %d", info)
+	}
+
+	buf := make([]byte, 1<<(info-24))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	for _, b := range buf {
+		arg = arg<<8 | uint64(b)
+	}
+	return major, info, arg, nil
+}
+
+// readCbor reads the next item, depth is the number of containers and tags
+// it is nested in
+func readCbor(r *bytes.Reader, depth int) (any, error) {
+	if depth > kMaxDepth {
+		return nil, errTooDeep
+	}
+	start := r.Size() - int64(r.Len())
+	major, info, arg, err := readCborHead(r)
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == kCborIndefinite
+
+	switch major {
+	case 4: // array
+		items := make([]any, 0)
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && atCborBreak(r) {
+				break
+			}
+			item, err := readCbor(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case 5: // map
+		m := make(orderedMap, 0)
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && atCborBreak(r) {
+				break
+			}
+			key, err := readCbor(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readCbor(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, mapEntry{key: key, value: value})
+		}
+		return m, nil
+
+	case 6: // tag
+		value, err := readCbor(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return taggedValue{tag: arg, value: value}, nil
+
+	case 7: // simple values and floats
+		switch {
+		case indefinite:
+			return nil, errors.New("unexpected break")
+		case info == 20:
+			return false, nil
+		case info == 21:
+			return true, nil
+		case info == 22:
+			return nil, nil
+		case info == 23:
+			return undefinedValue{}, nil
+		case info <= 24:
+			return simpleValue(arg), nil
+		}
+	}
+
+	// scalars, including chunked strings, are decoded by the cbor package
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var raw cbor.RawMessage
+	dec := cbor.NewDecoder(r)
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(start+int64(dec.NumBytesRead()), io.SeekStart); err != nil {
+		return nil, err
+	}
+	var v any
+	if err := cbor.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	if f, ok := v.(float32); ok {
+		return float64(f), nil
+	}
+	return v, nil
+}
+
+func atCborBreak(r *bytes.Reader) bool {
+	b, err := r.ReadByte()
+	if err != nil {
+		return false
+	}
+	if b == kCborBreak {
+		return true
+	}
+	r.UnreadByte()
+	return false
+}
@@ -0,0 +1,142 @@
+package subscription
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	kCompressionNone    = "none"
+	kCompressionAuto    = "auto"
+	kCompressionGzip    = "gzip"
+	kCompressionZlib    = "zlib"
+	kCompressionDeflate = "deflate"
+	kCompressionZstd    = "zstd"
+	kCompressionSnappy  = "snappy"
+)
+
+// maxDecompressed limits how large a decompressed payload may get so that a
+// small malicious payload can not exhaust the memory
+const maxDecompressed = 64 << 20
+
+var compressionChoices = []string{
+	kCompressionNone,
+	kCompressionAuto,
+	kCompressionGzip,
+	kCompressionZlib,
+	kCompressionDeflate,
+	kCompressionZstd,
+	kCompressionSnappy,
+}
+
+var snappyFrameMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+var (
+	zstdOnce    sync.Once
+	zstdDecoder *zstd.Decoder
+)
+
+func CompressionChoices() []string {
+	return compressionChoices
+}
+
+// Decompress returns the payload of msg decompressed as configured for the
+// subscription and the compression that was applied, "" if the payload was
+// used as received
+func (m Model) Decompress(msg Message) ([]byte, string, error) {
+	method := m.data.Compression
+	switch method {
+	case "", kCompressionNone:
+		return msg.data, "", nil
+	case kCompressionAuto:
+		method = detectCompression(msg.data)
+		if method == "" {
+			return msg.data, "", nil
+		}
+	}
+
+	data, err := decompress(msg.data, method)
+	if err != nil && m.data.Compression == kCompressionAuto && method == kCompressionZlib {
+		// the zlib header is only two bytes which some plain payloads start
+		// with as well
+		return msg.data, "", nil
+	}
+	if err != nil {
+		return nil, method, fmt.Errorf("%s decompression failed: %w", method, err)
+	}
+	return data, method, nil
+}
+
+// detectCompression recognises compressed payloads by their magic numbers.
+// Raw deflate and unframed snappy have none and must be chosen explicitly.
+func detectCompression(data []byte) string {
+	switch {
+	case len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b:
+		return kCompressionGzip
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return kCompressionZstd
+	case bytes.HasPrefix(data, snappyFrameMagic):
+		return kCompressionSnappy
+	case len(data) >= 2 && data[0]&0x0f == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0:
+		return kCompressionZlib
+	}
+	return ""
+}
+
+func decompress(data []byte, method string) ([]byte, error) {
+	var r io.Reader
+	switch method {
+	case kCompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case kCompressionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case kCompressionDeflate:
+		r = flate.NewReader(bytes.NewReader(data))
+	case kCompressionZstd:
+		zstdOnce.Do(func() {
+			zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressed), zstd.WithDecoderConcurrency(1))
+		})
+		return zstdDecoder.DecodeAll(data, nil)
+	case kCompressionSnappy:
+		if bytes.HasPrefix(data, snappyFrameMagic) {
+			r = snappy.NewReader(bytes.NewReader(data))
+			break
+		}
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecompressed {
+			return nil, errors.New("decompressed payload too large")
+		}
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", method)
+	}
+
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressed+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressed {
+		return nil, errors.New("decompressed payload too large")
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package subscription
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compressWith(t *testing.T, data []byte, newWriter func(w io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := newWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte(`{"temp":21.5}`)
+	gzipped := compressWith(t, plain, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	zlibbed := compressWith(t, plain, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+	deflated := compressWith(t, plain, func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+	zstded := compressWith(t, plain, func(w io.Writer) io.WriteCloser {
+		zw, _ := zstd.NewWriter(w)
+		return zw
+	})
+	framedSnappy := compressWith(t, plain, func(w io.Writer) io.WriteCloser { return snappy.NewBufferedWriter(w) })
+	bomb := compressWith(t, make([]byte, maxDecompressed+1), func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+	tests := []struct {
+		name        string
+		compression string
+		payload     []byte
+		want        []byte
+		method      string // reported as applied
+		err         string // part of the error, empty if it decompresses
+	}{
+		{"none", "", gzipped, gzipped, "", ""},
+		{"gzip", "gzip", gzipped, plain, "gzip", ""},
+		{"zlib", "zlib", zlibbed, plain, "zlib", ""},
+		{"deflate", "deflate", deflated, plain, "deflate", ""},
+		{"zstd", "zstd", zstded, plain, "zstd", ""},
+		{"snappy block", "snappy", snappy.Encode(nil, plain), plain, "snappy", ""},
+		{"snappy framed", "snappy", framedSnappy, plain, "snappy", ""},
+		{"auto gzip", "auto", gzipped, plain, "gzip", ""},
+		{"auto zlib", "auto", zlibbed, plain, "zlib", ""},
+		{"auto zstd", "auto", zstded, plain, "zstd", ""},
+		{"auto framed snappy", "auto", framedSnappy, plain, "snappy", ""},
+		{"auto plain", "auto", plain, plain, "", ""},
+		// 0x78 0x9c is a zlib header but the rest is not a zlib stream
+		{"auto zlib lookalike", "auto", []byte("x\x9c plain"), []byte("x\x9c plain"), "", ""},
+		{"not gzip", "gzip", plain, nil, "gzip", "gzip decompression failed"},
+		{"too large", "gzip", bomb, nil, "gzip", "decompressed payload too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := NewModel(Data{Compression: tt.compression})
+			got, method, err := sub.Decompress(newMessage(client.Message{Payload: tt.payload}, time.Now(), Observation{}))
+			if method != tt.method {
+				t.Errorf("method %q, want %q", method, tt.method)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decompressed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package subscription
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+)
+
+// DecodeContext is what a Decoder knows about the message being decoded
+type DecodeContext struct {
+	Topic      string
+	Properties *client.Properties
+	Sub        Data     // the subscription the message was received on
+	Session    *Session // of the connection, nil if unknown
+
+	Observation Observation // of the session when the message was received
+}
+
+// Decoder turns a raw payload into readable text for the message pane.
+// Decoders are registered by name, which is stored as the Format of a
+// subscription.
+type Decoder interface {
+	Name() string
+	Decode(payload []byte, ctx DecodeContext) (string, error)
+}
+
+var (
+	decodersMu   sync.RWMutex
+	decoders     = make(map[string]Decoder)
+	decoderNames []string // in registration order
+)
+
+// RegisterDecoder makes a decoder available as a subscription format, it
+// panics if a decoder with the same name is already registered
+func RegisterDecoder(d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if _, ok := decoders[d.Name()]; ok {
+		panic(fmt.Sprintf("decoder %s registered twice", d.Name()))
+	}
+	decoders[d.Name()] = d
+	decoderNames = append(decoderNames, d.Name())
+}
+
+func LookupDecoder(name string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, ok := decoders[name]
+	return d, ok
+}
+
+// FormatChoices returns the names of the registered decoders
+func FormatChoices() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	return slices.Clone(decoderNames)
+}
+
+// Decode renders the payload of msg with the decoder of the subscription's
+// format, falling back to a hex dump if it can not be decoded
+func (m Model) Decode(msg Message, session *Session) string {
+	s, err := m.decode(msg, session)
+	if err != nil {
+		return fmt.Sprintf("%s\n\n%s", err, hex.Dump(msg.data))
+	}
+	return s
+}
+
+// Preview is the decoded payload of msg on a single line, or the raw payload
+// if it can not be decoded
+func (m Model) Preview(msg Message, session *Session) string {
+	s, err := m.decode(msg, session)
+	if err != nil {
+		return Preview(msg.data)
+	}
+	return Preview([]byte(s))
+}
+
+func (m Model) decode(msg Message, session *Session) (string, error) {
+	name := m.data.Format
+	if name == "" {
+		name = kFormatNone
+	}
+	d, ok := LookupDecoder(name)
+	if !ok {
+		return "", fmt.Errorf("unknown format %q", name)
+	}
+
+	data, _, err := m.Decompress(msg)
+	if err != nil {
+		return "", err
+	}
+	s, err := d.Decode(data, DecodeContext{
+		Topic:      msg.recvTopic,
+		Properties: msg.properties,
+		Sub:        m.data,
+		Session:    session,
+
+		Observation: msg.observation,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s decoding failed: %w", name, err)
+	}
+	return s, nil
+}
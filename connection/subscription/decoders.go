@@ -0,0 +1,106 @@
+package subscription
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	kFormatNone = "none"
+	kFormatJson = "json"
+)
+
+func init() {
+	RegisterDecoder(noneDecoder{})
+	RegisterDecoder(jsonDecoder{})
+	RegisterDecoder(hexDecoder{})
+	RegisterDecoder(base64Decoder{})
+	RegisterDecoder(utf8Decoder{})
+}
+
+// noneDecoder shows the payload as it is
+type noneDecoder struct{}
+
+func (noneDecoder) Name() string { return kFormatNone }
+func (noneDecoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	return string(payload), nil
+}
+
+// jsonDecoder indents JSON payloads
+type jsonDecoder struct{}
+
+func (jsonDecoder) Name() string { return kFormatJson }
+func (jsonDecoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	var b bytes.Buffer
+	err := json.Indent(&b, payload, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return b.String(), nil
+}
+
+// hexDecoder shows a hex dump with offsets and the printable characters
+type hexDecoder struct{}
+
+func (hexDecoder) Name() string { return "hex" }
+func (hexDecoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	return hex.Dump(payload), nil
+}
+
+// base64Decoder decodes base64 text payloads, binary results are shown with
+// escapes like the utf8 decoder
+type base64Decoder struct{}
+
+func (base64Decoder) Name() string { return "base64" }
+func (base64Decoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	text := strings.TrimSpace(string(payload))
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		// payloads are often URL safe or unpadded
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(text, "="))
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+	return escapeUtf8(data), nil
+}
+
+// utf8Decoder shows the payload as text with invalid bytes and control
+// characters escaped
+type utf8Decoder struct{}
+
+func (utf8Decoder) Name() string { return "utf8" }
+func (utf8Decoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	return escapeUtf8(payload), nil
+}
+
+func escapeUtf8(data []byte) string {
+	var b strings.Builder
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, data[0])
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case !unicode.IsPrint(r) && !unicode.IsSpace(r), r == '\r':
+			if r < 0x80 {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+		data = data[size:]
+	}
+	return b.String()
+}
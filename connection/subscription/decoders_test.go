@@ -0,0 +1,77 @@
+package subscription
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDecoders(t *testing.T) {
+	deepCbor := bytes.Repeat([]byte{0x81}, 5<<20) // arrays of one array
+	deepMsgpack := bytes.Repeat([]byte{0x91}, 5<<20)
+
+	tests := []struct {
+		name    string
+		format  string
+		payload []byte
+		want    string // decoded text, or part of the error
+		err     bool
+	}{
+		{"json", "json", []byte(`{"a":1}`), "{\n  \"a\": 1\n}", false},
+		{"invalid json", "json", []byte(`{"a":`), "", true},
+		{"hex", "hex", []byte{0x00, 0xff}, hex.Dump([]byte{0x00, 0xff}), false},
+		{"utf8 escapes invalid bytes", "utf8", []byte("a\xffb"), `a\xffb`, false},
+
+		{"cbor map", "cbor", []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x82, 0xf5, 0xf6}, "\"a\": 1\n\"b\":\n  - true\n  - null", false},
+		{"cbor tag", "cbor", []byte{0xc1, 0x1a, 0x00, 0x00, 0x00, 0x00}, "tag(1 epoch time) 0", false},
+		{"cbor indefinite array", "cbor", []byte{0x9f, 0x01, 0x02, 0xff}, "- 1\n- 2", false},
+		{"cbor trailing bytes", "cbor", []byte{0x01, 0x02}, "1 bytes after the CBOR item", true},
+		{"cbor truncated", "cbor", []byte{0x82, 0x01}, "invalid CBOR", true},
+		{"cbor too deep", "cbor", deepCbor, "nested deeper than 512 levels", true},
+		{"cbor huge array header", "cbor", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "invalid CBOR", true},
+
+		{"msgpack map", "msgpack", []byte{0x82, 0xa1, 'b', 0x01, 0xa1, 'a', 0x92, 0xc3, 0xc0}, "\"b\": 1\n\"a\":\n  - true\n  - null", false},
+		{"msgpack timestamp", "msgpack", []byte{0xd6, 0xff, 0x00, 0x00, 0x00, 0x00}, "ext(-1 timestamp) 1970-01-01T00:00:00Z", false},
+		{"msgpack trailing bytes", "msgpack", []byte{0x01, 0x02}, "1 bytes after the MessagePack value", true},
+		{"msgpack too deep", "msgpack", deepMsgpack, "nested deeper than 512 levels", true},
+		{"msgpack huge map header", "msgpack", []byte{0xdf, 0x7f, 0xff, 0xff, 0xff}, "map of 2147483647 entries is longer than the 0 bytes left", true},
+		{"msgpack huge array header", "msgpack", []byte{0xdd, 0x7f, 0xff, 0xff, 0xff, 0x01}, "array of 2147483647 items is longer than the 1 bytes left", true},
+		{"msgpack huge ext header", "msgpack", []byte{0xc9, 0x7f, 0xff, 0xff, 0xff, 0x01}, "extension of 2147483647 bytes is longer than the 0 bytes left", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := LookupDecoder(tt.format)
+			if !ok {
+				t.Fatalf("no decoder %s", tt.format)
+			}
+			got, err := d.Decode(tt.payload, DecodeContext{})
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("error %v, want one containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("decoded\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTreeDepth(t *testing.T) {
+	var v any = 1
+	for range kMaxDepth + 2 {
+		v = []any{v}
+	}
+	if _, err := renderTree(v); err != errTooDeep {
+		t.Errorf("error %v rendering %d nested arrays, want %v", err, kMaxDepth+2, errTooDeep)
+	}
+	if _, err := renderTree([]any{[]any{1}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+// extraction expressions run while rendering, so a runaway expression such
+// as repeat(.) must not block the program
+const (
+	kExtractTimeout   = 50 * time.Millisecond
+	kExtractMaxValues = 100
+)
+
+var (
+	expressionsMu sync.Mutex
+	expressions   = make(map[string]*gojq.Code)
+)
+
+// CompileExpression compiles a jq expression, compiled expressions are cached
+// as they are evaluated for every message shown
+func CompileExpression(expr string) (*gojq.Code, error) {
+	expressionsMu.Lock()
+	defer expressionsMu.Unlock()
+	if code, ok := expressions[expr]; ok {
+		return code, nil
+	}
+
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	expressions[expr] = code
+	return code, nil
+}
+
+// Evaluate runs the jq expression expr on the decoded payload of msg, which
+// has to be JSON
+func (m Model) Evaluate(msg Message, session *Session, expr string) ([]any, error) {
+	code, err := CompileExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	content, err := m.decode(msg, session)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var input any
+	if err := dec.Decode(&input); err != nil {
+		return nil, errors.New("payload is not JSON")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kExtractTimeout)
+	defer cancel()
+	values := make([]any, 0, 1)
+	iter := code.RunWithContext(ctx, input)
+	for len(values) < kExtractMaxValues {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				break
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("expression took longer than %s", kExtractTimeout)
+			}
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// FormatValues renders the results of an expression like jq -r does,
+// containers are indented if multiline is set
+func FormatValues(values []any, multiline bool) string {
+	lines := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			lines = append(lines, s)
+			continue
+		}
+		b := &bytes.Buffer{}
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false)
+		if multiline {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(v); err != nil {
+			lines = append(lines, fmt.Sprint(v))
+			continue
+		}
+		lines = append(lines, strings.TrimSuffix(b.String(), "\n"))
+	}
+	if multiline {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines, ", ")
+}
@@ -0,0 +1,80 @@
+package subscription
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		payload string
+		expr    string
+		want    string // values formatted on one line
+		count   int    // of the values, checked if not 0
+		err     string // part of the error, empty if it evaluates
+	}{
+		{"field", kFormatJson, `{"temp":21.5}`, ".temp", "21.5", 0, ""},
+		{"large numbers are kept", kFormatJson, `{"id":12345678901234567890}`, ".id", "12345678901234567890", 0, ""},
+		{"strings are not quoted", kFormatJson, `{"unit":"C"}`, ".unit", "C", 0, ""},
+		{"several values", kFormatJson, `[1,2,3]`, ".[]", "1, 2, 3", 0, ""},
+		{"object", kFormatJson, `{"a":{"b":"<1>"}}`, ".a", `{"b":"<1>"}`, 0, ""},
+		{"no values", kFormatJson, `{}`, "empty", "", 0, ""},
+		{"halt", kFormatJson, `[1,2]`, ".[0], halt, .[1]", "1", 0, ""},
+		{"values are limited", kFormatJson, `0`, "repeat(.)", "", kExtractMaxValues, ""},
+		{"invalid expression", kFormatJson, `{}`, ".[", "", 0, "invalid expression"},
+		{"not json", kFormatNone, `temp=21.5`, ".temp", "", 0, "payload is not JSON"},
+		{"runtime error", kFormatJson, `{"a":"x"}`, ".a + 1", "", 0, "cannot add"},
+		{"timeout", kFormatJson, `0`, "last(range(1e12))", "", 0, "expression took longer than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := NewModel(Data{Format: tt.format})
+			msg := newMessage(client.Message{Payload: []byte(tt.payload)}, time.Now(), Observation{})
+			values, err := sub.Evaluate(msg, NewSession(), tt.expr)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.count != 0 {
+				if len(values) != tt.count {
+					t.Errorf("%d values, want %d", len(values), tt.count)
+				}
+				return
+			}
+			if got := FormatValues(values, false); got != tt.want {
+				t.Errorf("values %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValues(t *testing.T) {
+	values := []any{"text", 1, map[string]any{"a": []any{1, 2}}}
+	tests := []struct {
+		name      string
+		multiline bool
+		want      string
+	}{
+		{"single line", false, `text, 1, {"a":[1,2]}`},
+		{"multiline", true, "text\n1\n{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatValues(values, tt.multiline); got != tt.want {
+				t.Errorf("formatted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package subscription
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+const kMsgpackTimestampExt = -1
+
+func init() {
+	RegisterDecoder(msgpackDecoder{})
+}
+
+// msgpackDecoder renders MessagePack payloads as a tree, keeping the order of
+// map keys and showing extension types with their raw data
+type msgpackDecoder struct{}
+
+func (msgpackDecoder) Name() string { return "msgpack" }
+
+func (msgpackDecoder) Decode(payload []byte, _ DecodeContext) (string, error) {
+	r := bytes.NewReader(payload)
+	// the decoder reads r directly, so r.Len() is what is left to decode
+	v, err := readMsgpack(msgpack.NewDecoder(r), r, 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid MessagePack: %w", err)
+	}
+	if r.Len() > 0 {
+		return "", fmt.Errorf("%d bytes after the MessagePack value", r.Len())
+	}
+	return renderTree(v)
+}
+
+// readMsgpack reads the next value, depth is the number of containers it is
+// nested in. The lengths in the headers are checked against the bytes left
+// before anything is allocated for them.
+func readMsgpack(d *msgpack.Decoder, r *bytes.Reader, depth int) (any, error) {
+	if depth > kMaxDepth {
+		return nil, errTooDeep
+	}
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err := d.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		// a key and a value take at least a byte each
+		if n > r.Len()/2 {
+			return nil, fmt.Errorf("map of %d entries is longer than the %d bytes left", n, r.Len())
+		}
+		m := make(orderedMap, 0, max(n, 0))
+		for range n {
+			key, err := readMsgpack(d, r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readMsgpack(d, r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, mapEntry{key: key, value: value})
+		}
+		return m, nil
+
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err := d.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > r.Len() {
+			return nil, fmt.Errorf("array of %d items is longer than the %d bytes left", n, r.Len())
+		}
+		items := make([]any, 0, max(n, 0))
+		for range n {
+			item, err := readMsgpack(d, r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case msgpcode.IsFixedExt(c) || msgpcode.IsExt(c):
+		typ, n, err := d.DecodeExtHeader()
+		if err != nil {
+			return nil, err
+		}
+		if n > r.Len() {
+			return nil, fmt.Errorf("extension of %d bytes is longer than the %d bytes left", n, r.Len())
+		}
+		data := make([]byte, n)
+		if err := d.ReadFull(data); err != nil {
+			return nil, err
+		}
+		ext := extValue{typ: typ, data: data}
+		if typ == kMsgpackTimestampExt {
+			if t, ok := msgpackTimestamp(data); ok {
+				ext.name, ext.value = "timestamp", t
+			}
+		}
+		return ext, nil
+	}
+
+	return d.DecodeInterface()
+}
+
+// msgpackTimestamp decodes the data of the timestamp extension type
+func msgpackTimestamp(data []byte) (time.Time, bool) {
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), true
+	case 8:
+		n := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(n&0x3ffffffff), int64(n>>34)).UTC(), true
+	case 12:
+		nsec := binary.BigEndian.Uint32(data)
+		sec := binary.BigEndian.Uint64(data[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,47 @@
+package subscription
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const kPreviewLen = 256
+
+// Preview flattens the start of a payload onto a single line, replacing
+// anything which is not printable
+func Preview(data []byte) string {
+	s := string(data[:min(len(data), kPreviewLen)])
+	s = strings.Map(func(r rune) rune {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return '.'
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// IsText reports whether s can be shown as is, that is it is valid UTF-8
+// without control characters other than tabs and line breaks
+func IsText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '\n' || r == '\t':
+		case r == '\r' && strings.HasPrefix(s[i+1:], "\n"):
+		case !unicode.IsPrint(r) && !unicode.IsSpace(r), unicode.IsControl(r):
+			return false
+		}
+	}
+	return true
+}
+
+// HexDump shows data with offsets, hex bytes and a gutter of the printable
+// characters
+func HexDump(data []byte) string {
+	return fmt.Sprintf("%d bytes\n\n%s", len(data), hex.Dump(data))
+}
@@ -0,0 +1,227 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const kFormatProtobuf = "protobuf"
+
+const (
+	// payloads are decoded for every row shown, so the file is only checked
+	// for changes this often
+	kProtoCheckInterval = time.Second
+	// message types kept loaded, the one used least recently is dropped
+	kMaxProtoTypes = 32
+)
+
+func init() {
+	RegisterDecoder(&protobufDecoder{types: make(map[protoType]*protoDecoder)})
+}
+
+type protoType struct {
+	file    string
+	message string
+}
+
+// protobufDecoder decodes protobuf payloads with the descriptor file and
+// message type of the subscription
+type protobufDecoder struct {
+	mu    sync.Mutex
+	types map[protoType]*protoDecoder
+	uses  uint64 // number of decodes, orders the types by their last use
+}
+
+func (d *protobufDecoder) Name() string { return kFormatProtobuf }
+
+func (d *protobufDecoder) Decode(payload []byte, ctx DecodeContext) (string, error) {
+	t := protoType{file: ctx.Sub.ProtoFile, message: ctx.Sub.ProtoMessage}
+	if t.file == "" || t.message == "" {
+		return "", errors.New("no proto file or message type set for the subscription")
+	}
+
+	d.mu.Lock()
+	pd, ok := d.types[t]
+	if !ok {
+		if len(d.types) >= kMaxProtoTypes {
+			d.evict()
+		}
+		pd = newProtoDecoder(t.file, t.message)
+		d.types[t] = pd
+	}
+	d.uses++
+	pd.used = d.uses
+	d.mu.Unlock()
+
+	return pd.decode(payload)
+}
+
+// evict drops the message type used least recently
+func (d *protobufDecoder) evict() {
+	var oldest protoType
+	used := uint64(0)
+	for t, pd := range d.types {
+		if used == 0 || pd.used < used {
+			oldest, used = t, pd.used
+		}
+	}
+	delete(d.types, oldest)
+}
+
+// protoDecoder decodes payloads of one message type, the descriptors are only
+// loaded when the first message is decoded and again once the file changed
+type protoDecoder struct {
+	file    string // FileDescriptorSet or .proto file
+	message string // fully qualified message name
+	used    uint64 // protobufDecoder.uses when last used
+
+	mu      sync.Mutex
+	loaded  bool
+	checked time.Time // when the file was last checked for changes
+	modTime time.Time // of the file when it was loaded
+	desc    protoreflect.MessageDescriptor
+	types   *dynamicpb.Types
+	err     error
+}
+
+func newProtoDecoder(file string, message string) *protoDecoder {
+	return &protoDecoder{file: file, message: message}
+}
+
+// decode decodes payload into indented JSON
+func (d *protoDecoder) decode(payload []byte) (string, error) {
+	desc, types, err := d.descriptors()
+	if err != nil {
+		return "", err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	err = proto.UnmarshalOptions{Resolver: types}.Unmarshal(payload, msg)
+	if err != nil {
+		return "", fmt.Errorf("not a valid %s: %w", d.message, err)
+	}
+	data, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", Resolver: types}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// descriptors returns the loaded descriptors, a file which failed to load is
+// only tried again once it was modified
+func (d *protoDecoder) descriptors() (protoreflect.MessageDescriptor, *dynamicpb.Types, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if d.loaded && now.Sub(d.checked) < kProtoCheckInterval {
+		return d.desc, d.types, d.err
+	}
+	d.checked = now
+
+	info, err := os.Stat(d.file)
+	if err != nil {
+		// loaded again once the file is back
+		d.loaded, d.modTime = true, time.Time{}
+		d.desc, d.types, d.err = nil, nil, fmt.Errorf("could not read %s: %w", d.file, err)
+		return nil, nil, d.err
+	}
+	if !d.loaded || !info.ModTime().Equal(d.modTime) {
+		d.loaded, d.modTime = true, info.ModTime()
+		d.desc, d.types = nil, nil
+		d.load()
+	}
+	return d.desc, d.types, d.err
+}
+
+func (d *protoDecoder) load() {
+	var set *descriptorpb.FileDescriptorSet
+	if strings.HasSuffix(d.file, ".proto") {
+		set, d.err = compileProto(d.file)
+	} else {
+		set, d.err = readDescriptorSet(d.file)
+	}
+	if d.err != nil {
+		return
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		d.err = fmt.Errorf("invalid descriptors in %s: %w", d.file, err)
+		return
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(d.message))
+	if err != nil {
+		d.err = fmt.Errorf("message type %s not found in %s", d.message, d.file)
+		return
+	}
+	msgDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		d.err = fmt.Errorf("%s is not a message type", d.message)
+		return
+	}
+	d.desc = msgDesc
+	d.types = dynamicpb.NewTypes(files)
+}
+
+// readDescriptorSet reads a FileDescriptorSet as written by
+// protoc --descriptor_set_out --include_imports
+func readDescriptorSet(file string) (*descriptorpb.FileDescriptorSet, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read descriptor set: %w", err)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	err = proto.Unmarshal(data, set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", file, err)
+	}
+	return set, nil
+}
+
+// compileProto compiles a .proto file, imports are resolved relative to its
+// directory and the well known types are built in
+func compileProto(file string) (*descriptorpb.FileDescriptorSet, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{filepath.Dir(file)},
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), filepath.Base(file))
+	if err != nil {
+		return nil, fmt.Errorf("could not compile %s: %w", file, err)
+	}
+
+	// dependencies are added before the files importing them
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range files {
+		add(fd)
+	}
+	return set, nil
+}
@@ -0,0 +1,80 @@
+package subscription
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProtoDecoderReloadsChangedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "reading.proto")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payload := []byte{0x08, 0x96, 0x01} // value: 150
+
+	now := time.Now()
+	write(`syntax = "proto3"; package test; message Reading { int32 value = 1 }`, now.Add(-time.Minute))
+	d := newProtoDecoder(file, "test.Reading")
+	if _, err := d.decode(payload); err == nil || !strings.Contains(err.Error(), "could not compile") {
+		t.Fatalf("error %v decoding with an invalid file, want a compile error", err)
+	}
+
+	write(`syntax = "proto3"; package test; message Reading { int32 value = 1; }`, now)
+	if _, err := d.decode(payload); err == nil {
+		t.Fatal("file checked again within the check interval")
+	}
+	d.checked = time.Time{}
+	got, err := d.decode(payload)
+	if err != nil {
+		t.Fatalf("file is not loaded again once fixed: %v", err)
+	}
+	// protojson randomly adds spaces to keep its output unstable
+	if !strings.Contains(strings.Join(strings.Fields(got), ""), `"value":150`) {
+		t.Errorf("decoded %s, want value 150", got)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.decode(payload); err != nil {
+		t.Errorf("file checked again within the check interval: %v", err)
+	}
+	d.checked = time.Time{}
+	if _, err := d.decode(payload); err == nil {
+		t.Error("no error decoding after the file was removed")
+	}
+}
+
+func TestProtobufDecoderEvictsLeastRecentlyUsed(t *testing.T) {
+	d := &protobufDecoder{types: make(map[protoType]*protoDecoder)}
+	decode := func(i int) {
+		ctx := DecodeContext{Sub: Data{ProtoFile: "missing.proto", ProtoMessage: fmt.Sprintf("test.M%d", i)}}
+		d.Decode(nil, ctx)
+	}
+
+	for i := range kMaxProtoTypes {
+		decode(i)
+	}
+	decode(0)
+	decode(kMaxProtoTypes)
+
+	if len(d.types) != kMaxProtoTypes {
+		t.Errorf("%d message types kept, want %d", len(d.types), kMaxProtoTypes)
+	}
+	for i, want := range map[int]bool{0: true, 1: false, 2: true, kMaxProtoTypes: true} {
+		pt := protoType{file: "missing.proto", message: fmt.Sprintf("test.M%d", i)}
+		if _, ok := d.types[pt]; ok != want {
+			t.Errorf("test.M%d kept: %t, want %t", i, ok, want)
+		}
+	}
+}
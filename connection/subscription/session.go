@@ -0,0 +1,31 @@
+package subscription
+
+import (
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/sparkplug"
+)
+
+// Session is decoder state shared by all subscriptions of a connection, it is
+// fed with every message the connection receives
+type Session struct {
+	Sparkplug *sparkplug.Tracker
+}
+
+func NewSession() *Session {
+	return &Session{Sparkplug: sparkplug.NewTracker()}
+}
+
+// Observation is the session state a message is decoded with, taken when the
+// message is received and kept with it
+type Observation struct {
+	SparkplugAliases sparkplug.Aliases
+}
+
+// Observe records what decoders need to know about msg before it is routed
+// to the subscriptions and returns the state to keep with it
+func (s *Session) Observe(msg client.Message, at time.Time) Observation {
+	s.Sparkplug.Observe(msg.Topic, msg.Payload, at)
+	return Observation{SparkplugAliases: s.Sparkplug.Aliases(msg.Topic)}
+}
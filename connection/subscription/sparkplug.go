@@ -0,0 +1,21 @@
+package subscription
+
+import "github.com/OmegaRelay/mqtt-tui/connection/sparkplug"
+
+func init() {
+	RegisterDecoder(sparkplugDecoder{})
+}
+
+// sparkplugDecoder decodes Sparkplug B payloads, resolving metric aliases with
+// the births seen on the connection before the message was received
+type sparkplugDecoder struct{}
+
+func (sparkplugDecoder) Name() string { return "sparkplug" }
+
+func (sparkplugDecoder) Decode(payload []byte, ctx DecodeContext) (string, error) {
+	var tracker *sparkplug.Tracker
+	if ctx.Session != nil {
+		tracker = ctx.Session.Sparkplug
+	}
+	return sparkplug.Render(ctx.Topic, payload, tracker, ctx.Observation.SparkplugAliases)
+}
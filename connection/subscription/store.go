@@ -0,0 +1,131 @@
+package subscription
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaxMessages is kept when Data.MaxMessages is not set
+const DefaultMaxMessages = 10000
+
+// store keeps the received messages of a subscription in a ring buffer,
+// dropping the oldest messages once any of the retention limits is exceeded.
+// The buffer grows as needed up to maxMessages so that quiet subscriptions do
+// not reserve the full capacity.
+type store struct {
+	mu sync.Mutex
+
+	buf   []Message
+	head  int // index of the oldest message
+	count int
+	bytes int
+
+	maxMessages int
+	maxBytes    int           // 0 is unlimited
+	maxAge      time.Duration // 0 is unlimited
+}
+
+func newStore(data Data) *store {
+	s := &store{
+		maxMessages: data.MaxMessages,
+		maxBytes:    data.MaxBytes,
+		maxAge:      data.MaxAge,
+	}
+	if s.maxMessages <= 0 {
+		s.maxMessages = DefaultMaxMessages
+	}
+	return s
+}
+
+func (s *store) push(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == s.maxMessages {
+		s.dropOldest()
+	}
+	if s.count == len(s.buf) {
+		s.grow()
+	}
+	s.buf[(s.head+s.count)%len(s.buf)] = msg
+	s.count++
+	s.bytes += len(msg.data)
+
+	// always keep the newest message, even if it is over the byte limit
+	for s.maxBytes > 0 && s.bytes > s.maxBytes && s.count > 1 {
+		s.dropOldest()
+	}
+	s.expire(msg.recvAt)
+}
+
+// grow doubles the capacity of the buffer, capped at maxMessages, moving the
+// oldest message to the start
+func (s *store) grow() {
+	capacity := min(max(2*len(s.buf), 16), s.maxMessages)
+	buf := make([]Message, capacity)
+	for i := range s.count {
+		buf[i] = s.buf[(s.head+i)%len(s.buf)]
+	}
+	s.buf = buf
+	s.head = 0
+}
+
+func (s *store) dropOldest() {
+	s.bytes -= len(s.buf[s.head].data)
+	s.buf[s.head] = Message{}
+	s.head = (s.head + 1) % len(s.buf)
+	s.count--
+}
+
+// expire drops messages older than maxAge relative to now
+func (s *store) expire(now time.Time) {
+	if s.maxAge <= 0 {
+		return
+	}
+	for s.count > 0 && now.Sub(s.buf[s.head].recvAt) > s.maxAge {
+		s.dropOldest()
+	}
+}
+
+func (s *store) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	return s.count
+}
+
+// at returns the i-th stored message, newest first, or the zero Message if
+// there are not that many
+func (s *store) at(i int) Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < 0 || i >= s.count {
+		return Message{}
+	}
+	return s.buf[(s.head+s.count-1-i)%len(s.buf)]
+}
+
+// messages returns a copy of the stored messages, newest first
+func (s *store) messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	messages := make([]Message, s.count)
+	for i := range s.count {
+		messages[i] = s.buf[(s.head+s.count-1-i)%len(s.buf)]
+	}
+	return messages
+}
+
+func (s *store) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = nil
+	s.head = 0
+	s.count = 0
+	s.bytes = 0
+}
@@ -0,0 +1,97 @@
+package subscription
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testMessage(n int, size int, at time.Time) Message {
+	return Message{recvTopic: fmt.Sprintf("t/%d", n), recvAt: at, data: make([]byte, size)}
+}
+
+func TestStoreRetention(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		data   Data
+		pushes int
+		size   int
+		age    time.Duration // between pushes, the last one is received now
+		want   []string      // topics kept, newest first
+	}{
+		{"under limits", Data{MaxMessages: 5}, 3, 1, 0, []string{"t/2", "t/1", "t/0"}},
+		{"max messages", Data{MaxMessages: 3}, 5, 1, 0, []string{"t/4", "t/3", "t/2"}},
+		{"max bytes", Data{MaxBytes: 25}, 5, 10, 0, []string{"t/4", "t/3"}},
+		{"newest over max bytes", Data{MaxBytes: 5}, 2, 10, 0, []string{"t/1"}},
+		{"max age", Data{MaxAge: 90 * time.Second}, 4, 1, time.Minute, []string{"t/3", "t/2"}},
+		{"grows past initial capacity", Data{MaxMessages: 20}, 18, 1, 0, []string{
+			"t/17", "t/16", "t/15", "t/14", "t/13", "t/12", "t/11", "t/10", "t/9",
+			"t/8", "t/7", "t/6", "t/5", "t/4", "t/3", "t/2", "t/1", "t/0",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(tt.data)
+			for i := range tt.pushes {
+				at := now.Add(-time.Duration(tt.pushes-1-i) * tt.age)
+				s.push(testMessage(i, tt.size, at))
+			}
+
+			messages := s.messages()
+			if len(messages) != len(tt.want) || s.len() != len(tt.want) {
+				t.Fatalf("kept %d messages (len %d), want %d", len(messages), s.len(), len(tt.want))
+			}
+			for i, topic := range tt.want {
+				if messages[i].recvTopic != topic {
+					t.Errorf("messages()[%d] is %s, want %s", i, messages[i].recvTopic, topic)
+				}
+				if got := s.at(i).recvTopic; got != topic {
+					t.Errorf("at(%d) is %s, want %s", i, got, topic)
+				}
+			}
+			if got := s.at(len(tt.want)); got.recvTopic != "" {
+				t.Errorf("at past the oldest message is %s, want the zero Message", got.recvTopic)
+			}
+		})
+	}
+}
+
+func BenchmarkPush(b *testing.B) {
+	for _, limit := range []int{100, DefaultMaxMessages} {
+		b.Run(fmt.Sprint(limit), func(b *testing.B) {
+			s := newStore(Data{MaxMessages: limit})
+			msg := testMessage(0, 64, time.Now())
+			b.ReportAllocs()
+			for range b.N {
+				s.push(msg)
+			}
+		})
+	}
+}
+
+func BenchmarkMessages(b *testing.B) {
+	for _, limit := range []int{100, DefaultMaxMessages} {
+		s := newStore(Data{MaxMessages: limit})
+		for i := range limit {
+			s.push(testMessage(i, 64, time.Now()))
+		}
+
+		b.Run(fmt.Sprintf("messages/%d", limit), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = s.messages()
+			}
+		})
+		// a page of the message table
+		b.Run(fmt.Sprintf("at/%d", limit), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for i := range min(s.len(), 40) {
+					_ = s.at(i)
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,11 @@
 package subscription
 
 import (
-	"bytes"
-	"encoding/json"
-	"sync"
 	"time"
 
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
 	"github.com/OmegaRelay/mqtt-tui/program"
-	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
 )
 
 type ReceivedMsg struct {
@@ -15,28 +13,41 @@ type ReceivedMsg struct {
 }
 
 type Message struct {
-	recvTopic string
-	recvAt    time.Time
-	data      []byte
+	recvTopic  string
+	recvAt     time.Time
+	data       []byte
+	qos        byte
+	retained   bool
+	duplicate  bool
+	messageId  uint16 // 0 for QoS 0 and messages without one in a capture
+	properties *client.Properties
+
+	observation Observation
 }
 
 type Data struct {
+	Id     string // stable identity, the name and topic can be edited
 	Name   string
 	Topic  string
 	Qos    byte
 	Format string
-}
 
-type Model struct {
-	data       Data
-	messages   chan []Message
-	messagesMu *sync.Mutex
-	messageIdx int
+	Compression string // applied before the format, "" or "none" leaves payloads as received
+	Extract     string // jq expression whose result is shown instead of the payload, e.g. .sensors[0].temp
+
+	// used by the protobuf format
+	ProtoFile    string // FileDescriptorSet or .proto file
+	ProtoMessage string // fully qualified message type, e.g. sensors.v1.Reading
+
+	// retention of received messages, the oldest are dropped first
+	MaxMessages int           // 0 uses DefaultMaxMessages
+	MaxBytes    int           // total payload size, 0 is unlimited
+	MaxAge      time.Duration // 0 is unlimited
 }
 
-var FormatChoices = []string{
-	"none",
-	"json",
+type Model struct {
+	data  Data
+	store *store
 }
 
 var qosChoices = []string{
@@ -46,13 +57,13 @@ var qosChoices = []string{
 }
 
 func NewModel(data Data) Model {
-	s := Model{
-		data:       data,
-		messages:   make(chan []Message, 1),
-		messagesMu: &sync.Mutex{},
+	if data.Id == "" {
+		data.Id = uuid.NewString()
+	}
+	return Model{
+		data:  data,
+		store: newStore(data),
 	}
-	s.messages <- make([]Message, 0)
-	return s
 }
 
 func QosChoices() []string {
@@ -63,52 +74,58 @@ func (m Model) Title() string       { return m.data.Name }
 func (m Model) Description() string { return m.data.Topic }
 func (m Model) FilterValue() string { return m.data.Topic }
 
-func (m Model) OnPubHandler(client mqtt.Client, msg mqtt.Message) {
-	var data []byte
-	data = msg.Payload()
-	switch m.data.Format {
-	case "json":
-		tmp := bytes.NewBuffer([]byte{})
-		err := json.Indent(tmp, data, "", "  ")
-		if err == nil {
-			data = tmp.Bytes()
-		}
-	}
-
-	m.messagesMu.Lock()
-	newMessage := Message{
-		recvTopic: msg.Topic(),
-		recvAt:    time.Now(),
-		data:      data,
-	}
-
-	messages := <-m.messages
-	messages = append([]Message{newMessage}, messages...)
-	m.messages <- messages
-	m.messagesMu.Unlock()
+// OnPubHandler stores the raw message with what the session observed when it
+// was received, it is only decoded when shown
+func (m Model) OnPubHandler(msg client.Message, obs Observation) {
+	m.store.push(newMessage(msg, time.Now(), obs))
 
 	program.Program().Send(ReceivedMsg{
 		Sub: m,
 	})
 }
 
+// Restore stores a message received at an earlier time, e.g. loaded from the
+// message log, without notifying the program
+func (m Model) Restore(msg client.Message, at time.Time, obs Observation) {
+	m.store.push(newMessage(msg, at, obs))
+}
+
+func newMessage(msg client.Message, at time.Time, obs Observation) Message {
+	return Message{
+		recvTopic:  msg.Topic,
+		recvAt:     at,
+		data:       msg.Payload,
+		qos:        msg.Qos,
+		retained:   msg.Retained,
+		duplicate:  msg.Duplicate,
+		messageId:  msg.MessageId,
+		properties: msg.Properties,
+
+		observation: obs,
+	}
+}
+
+// Messages returns a copy of the retained messages, newest first. Len and At
+// read single messages without copying them all.
 func (m Model) Messages() []Message {
-	m.messagesMu.Lock()
-	defer m.messagesMu.Unlock()
-	messages := <-m.messages
-	m.messages <- messages
+	return m.store.messages()
+}
+
+// Len returns the number of retained messages
+func (m Model) Len() int {
+	return m.store.len()
+}
 
-	return messages
+// At returns the i-th retained message, newest first. Messages may arrive or
+// be dropped after Len was called, past the oldest message the zero Message
+// is returned.
+func (m Model) At(i int) Message {
+	return m.store.at(i)
 }
 
 // Clears all messages
 func (m Model) Clear() {
-	m.messagesMu.Lock()
-	defer m.messagesMu.Unlock()
-
-	messages := <-m.messages
-	messages = make([]Message, 0)
-	m.messages <- messages
+	m.store.clear()
 }
 
 func (m Model) Data() Data { return m.data }
@@ -116,3 +133,10 @@ func (m Model) Data() Data { return m.data }
 func (m Message) RecvTopic() string { return m.recvTopic }
 func (m Message) RecvAt() time.Time { return m.recvAt }
 func (m Message) Data() []byte      { return m.data }
+func (m Message) Qos() byte         { return m.qos }
+func (m Message) Retained() bool    { return m.retained }
+func (m Message) Duplicate() bool   { return m.duplicate }
+func (m Message) MessageId() uint16 { return m.messageId }
+
+// Properties returns the MQTT v5 properties of the message, nil for v3.1.1
+func (m Message) Properties() *client.Properties { return m.properties }
@@ -0,0 +1,179 @@
+package subscription
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kMaxDepth is the deepest nesting of containers and tags which is decoded
+// and rendered, deeper payloads would overflow the stack
+const kMaxDepth = 512
+
+var errTooDeep = fmt.Errorf("nested deeper than %d levels", kMaxDepth)
+
+// values decoded from binary formats which have no plain Go equivalent, they
+// are rendered explicitly by renderTree
+
+type mapEntry struct {
+	key   any
+	value any
+}
+
+// orderedMap keeps the keys of a map in the order they were encoded
+type orderedMap []mapEntry
+
+type taggedValue struct {
+	tag   uint64
+	value any
+}
+
+type extValue struct {
+	typ  int8
+	data []byte
+
+	// set for extension types which are understood
+	name  string
+	value any
+}
+
+type simpleValue uint8
+
+type undefinedValue struct{}
+
+var cborTagNames = map[uint64]string{
+	0:     "date/time",
+	1:     "epoch time",
+	2:     "positive bignum",
+	3:     "negative bignum",
+	4:     "decimal fraction",
+	5:     "bigfloat",
+	21:    "base64url",
+	22:    "base64",
+	23:    "base16",
+	24:    "encoded CBOR",
+	32:    "URI",
+	33:    "base64url text",
+	34:    "base64 text",
+	35:    "regexp",
+	36:    "MIME",
+	37:    "UUID",
+	55799: "self-described CBOR",
+}
+
+// renderTree renders a decoded value as an indented tree, one scalar per line
+func renderTree(v any) (string, error) {
+	var b strings.Builder
+	if err := renderNode(&b, v, 0); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func renderNode(b *strings.Builder, v any, depth int) error {
+	if depth > kMaxDepth {
+		return errTooDeep
+	}
+	indent := strings.Repeat("  ", depth)
+	switch v := v.(type) {
+	case orderedMap:
+		if len(v) == 0 {
+			fmt.Fprintf(b, "%s{}\n", indent)
+		}
+		for _, e := range v {
+			if err := renderChild(b, indent+scalarString(e.key)+":", e.value, depth); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			fmt.Fprintf(b, "%s[]\n", indent)
+		}
+		for _, item := range v {
+			if err := renderChild(b, indent+"-", item, depth); err != nil {
+				return err
+			}
+		}
+	case taggedValue:
+		return renderChild(b, indent+tagString(v.tag), v.value, depth)
+	default:
+		fmt.Fprintf(b, "%s%s\n", indent, scalarString(v))
+	}
+	return nil
+}
+
+// renderChild writes prefix followed by a scalar on the same line, or by a
+// container on the lines below it
+func renderChild(b *strings.Builder, prefix string, v any, depth int) error {
+	for {
+		t, ok := v.(taggedValue)
+		if !ok {
+			break
+		}
+		prefix += " " + tagString(t.tag)
+		v = t.value
+	}
+	if !isContainer(v) {
+		fmt.Fprintf(b, "%s %s\n", prefix, scalarString(v))
+		return nil
+	}
+	fmt.Fprintf(b, "%s\n", prefix)
+	return renderNode(b, v, depth+1)
+}
+
+func isContainer(v any) bool {
+	switch v := v.(type) {
+	case orderedMap:
+		return len(v) > 0
+	case []any:
+		return len(v) > 0
+	case taggedValue:
+		return isContainer(v.value)
+	}
+	return false
+}
+
+func tagString(tag uint64) string {
+	if name, ok := cborTagNames[tag]; ok {
+		return fmt.Sprintf("tag(%d %s)", tag, name)
+	}
+	return fmt.Sprintf("tag(%d)", tag)
+}
+
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case undefinedValue:
+		return "undefined"
+	case simpleValue:
+		return fmt.Sprintf("simple(%d)", v)
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return fmt.Sprintf("h'%s' (%d bytes)", hex.EncodeToString(v), len(v))
+	case extValue:
+		if v.name != "" {
+			return fmt.Sprintf("ext(%d %s) %s", v.typ, v.name, scalarString(v.value))
+		}
+		return fmt.Sprintf("ext(%d) h'%s'", v.typ, hex.EncodeToString(v.data))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *big.Int:
+		return v.String()
+	case big.Int:
+		return v.String()
+	case orderedMap:
+		return "{}"
+	case []any:
+		return "[]"
+	case taggedValue:
+		// only reached for keys, which are rarely tagged
+		return fmt.Sprintf("%s %s", tagString(v.tag), scalarString(v.value))
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,148 @@
+package connection
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+)
+
+const kTlsVersionDefault = "default"
+
+var tlsVersionChoices = []string{
+	kTlsVersionDefault,
+	"1.0",
+	"1.1",
+	"1.2",
+	"1.3",
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func TlsVersionChoices() []string {
+	return tlsVersionChoices
+}
+
+// tlsConfig builds the TLS configuration used to connect to the broker from
+// the connection data. Any file which can not be loaded is reported as an
+// error rather than silently connecting without it.
+func (d Data) tlsConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName:         d.ServerName,
+		InsecureSkipVerify: !d.Authenticate,
+	}
+
+	var ok bool
+	tlsCfg.MinVersion, ok = parseTlsVersion(d.TlsMinVersion)
+	if !ok {
+		return nil, fmt.Errorf("invalid minimum TLS version %q", d.TlsMinVersion)
+	}
+	tlsCfg.MaxVersion, ok = parseTlsVersion(d.TlsMaxVersion)
+	if !ok {
+		return nil, fmt.Errorf("invalid maximum TLS version %q", d.TlsMaxVersion)
+	}
+	if tlsCfg.MinVersion != 0 && tlsCfg.MaxVersion != 0 && tlsCfg.MinVersion > tlsCfg.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above maximum %s", d.TlsMinVersion, d.TlsMaxVersion)
+	}
+
+	for _, proto := range strings.Split(d.Alpn, ",") {
+		proto = strings.TrimSpace(proto)
+		if proto != "" {
+			tlsCfg.NextProtos = append(tlsCfg.NextProtos, proto)
+		}
+	}
+
+	if d.CaFilePath != "" {
+		caPem, err := os.ReadFile(d.CaFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", d.CaFilePath)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if d.KeyFilePath != "" || d.CertFilePath != "" {
+		if d.KeyFilePath == "" || d.CertFilePath == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := loadKeyPair(d.CertFilePath, d.KeyFilePath, d.KeyPassword)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+func parseTlsVersion(version string) (uint16, bool) {
+	if version == "" || version == kTlsVersionDefault {
+		return 0, true
+	}
+	v, ok := tlsVersions[version]
+	return v, ok
+}
+
+// loadKeyPair loads a client certificate, decrypting its key with password
+// if the key is an encrypted PKCS#8 key. Keys with the legacy PEM encryption
+// of OpenSSL (Proc-Type: 4,ENCRYPTED) are insecure and not supported, they
+// can be converted with openssl pkcs8 -topk8.
+func loadKeyPair(certFile string, keyFile string, password string) (tls.Certificate, error) {
+	certPem, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not read certificate file: %w", err)
+	}
+	keyPem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM data found in key file %s", keyFile)
+	}
+
+	var key any
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if password == "" {
+			return tls.Certificate{}, errors.New("key file is encrypted but no key password was given")
+		}
+		key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+	case strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED"):
+		return tls.Certificate{}, fmt.Errorf("key file %s uses legacy PEM encryption, convert it to PKCS#8 with openssl pkcs8 -topk8", keyFile)
+	default:
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		return cert, nil
+	}
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not decrypt key file: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not load decrypted key: %w", err)
+	}
+	keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not load client certificate: %w", err)
+	}
+	return cert, nil
+}
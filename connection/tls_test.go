@@ -0,0 +1,93 @@
+package connection
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestLoadKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writePem(t, dir, "client.crt", &pem.Block{Type: "CERTIFICATE", Bytes: certDer})
+
+	plainDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedDer, err := pkcs8.MarshalPrivateKey(key, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainFile := writePem(t, dir, "plain.key", &pem.Block{Type: "PRIVATE KEY", Bytes: plainDer})
+	encryptedFile := writePem(t, dir, "encrypted.key", &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedDer})
+	legacyFile := writePem(t, dir, "legacy.key", &pem.Block{
+		Type:    "EC PRIVATE KEY",
+		Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-256-CBC,00000000000000000000000000000000"},
+		Bytes:   plainDer,
+	})
+
+	tests := []struct {
+		name     string
+		keyFile  string
+		password string
+		err      string // empty if the key pair loads
+	}{
+		{"plain", plainFile, "", ""},
+		{"pkcs8 encrypted", encryptedFile, "secret", ""},
+		{"pkcs8 wrong password", encryptedFile, "wrong", "could not decrypt key file"},
+		{"pkcs8 no password", encryptedFile, "", "no key password"},
+		{"legacy pem encryption", legacyFile, "secret", "legacy PEM encryption"},
+		{"missing", filepath.Join(dir, "missing.key"), "", "could not read key file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := loadKeyPair(certFile, tt.keyFile, tt.password)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if cert.PrivateKey == nil {
+					t.Fatal("no private key loaded")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func writePem(t *testing.T, dir string, name string, block *pem.Block) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
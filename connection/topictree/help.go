@@ -0,0 +1,55 @@
+package topictree
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keyMap struct {
+	Up        key.Binding
+	Down      key.Binding
+	Expand    key.Binding
+	Collapse  key.Binding
+	PageUp    key.Binding
+	PageDown  key.Binding
+	Subscribe key.Binding
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Expand, k.Collapse, k.Subscribe}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown},
+		{k.Expand, k.Collapse, k.Subscribe},
+	}
+}
+
+var Keys = keyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "move up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "move down"),
+	),
+	Expand: key.NewBinding(
+		key.WithKeys("right", "l", "enter"),
+		key.WithHelp("→/l/enter", "expand topic"),
+	),
+	Collapse: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "collapse topic"),
+	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page down"),
+	),
+	Subscribe: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "subscribe to topic and everything below"),
+	),
+}
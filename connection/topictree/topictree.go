@@ -0,0 +1,212 @@
+package topictree
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/scroll"
+	"github.com/OmegaRelay/mqtt-tui/connection/sparkplug"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const kRefreshInterval = 250 * time.Millisecond
+
+// SubscribeMsg asks the connection to add a subscription for a subtree, Id
+// is the one of the Model it comes from
+type SubscribeMsg struct {
+	Id   int64
+	Data subscription.Data
+}
+
+type tickMsg struct {
+	id int64
+}
+
+var lastId atomic.Int64
+
+var (
+	selectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	previewStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+)
+
+// Model explores a Tree, it redraws on a timer rather than for every message
+// so busy brokers do not flood the program with updates
+type Model struct {
+	id       int64
+	tree     *Tree
+	expanded map[string]bool
+	selected string // path of the selected topic
+	offset   int    // first visible row
+	height   int
+}
+
+func New(tree *Tree) Model {
+	return Model{
+		id:       lastId.Add(1),
+		tree:     tree,
+		expanded: make(map[string]bool),
+	}
+}
+
+func (m Model) Id() int64 {
+	return m.id
+}
+
+// SetHeight sets the height available to View
+func (m *Model) SetHeight(height int) {
+	m.height = height
+}
+
+// pageSize is the number of rows of the tree shown, the rest of the height is
+// used for the selected topic
+func (m Model) pageSize() int {
+	return max(3, m.height/2)
+}
+
+func (m Model) Init() tea.Cmd {
+	return m.tick()
+}
+
+func (m Model) tick() tea.Cmd {
+	return tea.Tick(kRefreshInterval, func(time.Time) tea.Msg {
+		return tickMsg{id: m.id}
+	})
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		if msg.id == m.id {
+			return m, m.tick()
+		}
+
+	case tea.KeyMsg:
+		rows := m.tree.rows(m.expanded)
+		if len(rows) == 0 {
+			break
+		}
+		idx := m.selectedIndex(rows)
+		switch {
+		case key.Matches(msg, Keys.Up):
+			idx--
+		case key.Matches(msg, Keys.Down):
+			idx++
+		case key.Matches(msg, Keys.PageUp):
+			idx -= m.pageSize()
+		case key.Matches(msg, Keys.PageDown):
+			idx += m.pageSize()
+		case key.Matches(msg, Keys.Expand):
+			if rows[idx].hasChildren {
+				m.expanded[rows[idx].path] = true
+			}
+		case key.Matches(msg, Keys.Collapse):
+			if rows[idx].expanded {
+				delete(m.expanded, rows[idx].path)
+			} else if sep := strings.LastIndex(rows[idx].path, "/"); sep >= 0 {
+				// already collapsed, move to the parent instead
+				m.selected = rows[idx].path[:sep]
+				return m, nil
+			}
+		case key.Matches(msg, Keys.Subscribe):
+			r := rows[idx]
+			data := subscription.Data{Name: r.path, Topic: r.path, Format: "none"}
+			if r.hasChildren {
+				// a multi-level wildcard also matches its parent level
+				data.Topic += "/#"
+				data.Name = data.Topic
+			}
+			if json.Valid(r.last) {
+				data.Format = "json"
+			} else if strings.HasPrefix(r.path, sparkplug.Namespace+"/") {
+				data.Format = "sparkplug"
+			}
+			return m, func() tea.Msg { return SubscribeMsg{Id: m.id, Data: data} }
+		}
+		idx = min(max(idx, 0), len(rows)-1)
+		m.selected = rows[idx].path
+		m.offset = scroll.Offset(idx, m.offset, m.pageSize())
+	}
+	return m, nil
+}
+
+func (m Model) selectedIndex(rows []row) int {
+	return max(slices.IndexFunc(rows, func(r row) bool { return r.path == m.selected }), 0)
+}
+
+// View renders the tree above the latest message of the selected topic
+func (m Model) View(borderStyle lipgloss.Style, width int) string {
+	rows := m.tree.rows(m.expanded)
+	idx := m.selectedIndex(rows)
+
+	// rows above the selection may have appeared since the last update
+	pageSize := m.pageSize()
+	offset := scroll.Offset(idx, min(m.offset, idx), pageSize)
+
+	lines := make([]string, 0, pageSize)
+	for i := offset; i < len(rows) && i < offset+pageSize; i++ {
+		lines = append(lines, rowView(rows[i], i == idx, width))
+	}
+	if len(rows) == 0 {
+		lines = append(lines, previewStyle.Render("no messages received yet"))
+	}
+	treeView := viewport.New(width, pageSize)
+	treeView.SetContent(strings.Join(lines, "\n"))
+
+	header := viewport.New(width, 1)
+	detail := viewport.New(width, max(1, m.height-pageSize-7))
+	if len(rows) > 0 {
+		r := rows[idx]
+		fields := []string{r.path, fmt.Sprintf("%d messages", r.count)}
+		if r.count > 0 {
+			fields = append(fields, r.lastAt.Format("2006-01-02 15:04:05.000"))
+		}
+		if r.retained {
+			fields = append(fields, "retained")
+		}
+		header.SetContent(strings.Join(fields, " │ "))
+		if subscription.IsText(string(r.last)) {
+			detail.SetContent(strings.ReplaceAll(string(r.last), "\r\n", "\n"))
+		} else {
+			detail.SetContent(subscription.HexDump(r.last))
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Top,
+		borderStyle.Render(treeView.View()),
+		borderStyle.Render(header.View()),
+		borderStyle.Render(detail.View()),
+	)
+}
+
+func rowView(r row, selected bool, width int) string {
+	marker := "  "
+	if r.hasChildren && r.expanded {
+		marker = "▾ "
+	} else if r.hasChildren {
+		marker = "▸ "
+	}
+	name := r.name
+	if name == "" {
+		name = "(empty)"
+	}
+
+	s := strings.Repeat("  ", r.depth) + marker + name + fmt.Sprintf(" (%d)", r.total)
+	if r.retained {
+		s += " R"
+	}
+	if selected {
+		s = selectedStyle.Render(s)
+	}
+	if r.count > 0 {
+		s += previewStyle.Render(" = " + subscription.Preview(r.last))
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(s)
+}
@@ -0,0 +1,190 @@
+package topictree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/subscription"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var testAt = time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+func testTree() *Tree {
+	tree := NewTree()
+	for i, msg := range []client.Message{
+		{Topic: "sensors/1/temp", Payload: []byte("20")},
+		{Topic: "sensors/1/temp", Payload: []byte("21")},
+		{Topic: "sensors/2/temp", Payload: []byte(`{"v":1}`)},
+		{Topic: "sensors/1", Payload: []byte("on"), Retained: true},
+		{Topic: "/leading", Payload: []byte{0x00}},
+		{Topic: "spBv1.0/g/NDATA/n", Payload: []byte{0x08, 0x01}},
+	} {
+		tree.Add(msg, testAt.Add(time.Duration(i)*time.Second))
+	}
+	return tree
+}
+
+// expandAll returns every path with children
+func expandAll(tree *Tree) map[string]bool {
+	expanded := make(map[string]bool)
+	for {
+		n := len(expanded)
+		for _, r := range tree.rows(expanded) {
+			if r.hasChildren {
+				expanded[r.path] = true
+			}
+		}
+		if len(expanded) == n {
+			return expanded
+		}
+	}
+}
+
+func TestTreeAdd(t *testing.T) {
+	// path, depth, total and count of every row, and the last payload
+	want := []string{
+		"'' 0 1 0",
+		"/leading 1 1 1 \x00",
+		"sensors 0 4 0",
+		"sensors/1 1 3 1 on R",
+		"sensors/1/temp 2 2 2 21",
+		"sensors/2 1 1 0",
+		`sensors/2/temp 2 1 1 {"v":1}`,
+		"spBv1.0 0 1 0",
+		"spBv1.0/g 1 1 0",
+		"spBv1.0/g/NDATA 2 1 0",
+		"spBv1.0/g/NDATA/n 3 1 1 \x08\x01",
+	}
+
+	tree := testTree()
+	rows := tree.rows(expandAll(tree))
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d", len(rows), len(want))
+	}
+	for i, r := range rows {
+		path := r.path
+		if path == "" {
+			path = "''"
+		}
+		got := fmt.Sprintf("%s %d %d %d", path, r.depth, r.total, r.count)
+		if r.count > 0 {
+			got += " " + string(r.last)
+		}
+		if r.retained {
+			got += " R"
+		}
+		if got != want[i] {
+			t.Errorf("row %d is %q, want %q", i, got, want[i])
+		}
+	}
+
+	if rows := tree.rows(nil); len(rows) != 3 {
+		t.Errorf("%d top level rows, want 3", len(rows))
+	}
+	if rows[4].lastAt != testAt.Add(time.Second) {
+		t.Errorf("last received at %s, want the time of the latest message", rows[4].lastAt)
+	}
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "pgdown":
+		return tea.KeyMsg{Type: tea.KeyPgDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		selected string
+		rows     int // visible
+	}{
+		{"first row", nil, "", 3},
+		{"down", []string{"j"}, "sensors", 3},
+		{"up stops at the top", []string{"j", "k", "k"}, "", 3},
+		{"down stops at the bottom", []string{"j", "j", "j"}, "spBv1.0", 3},
+		{"expand", []string{"j", "l", "j"}, "sensors/1", 5},
+		{"leaves do not expand", []string{"j", "l", "j", "l", "j", "l"}, "sensors/1/temp", 6},
+		{"collapse", []string{"j", "l", "h"}, "sensors", 3},
+		{"collapse a collapsed row selects its parent", []string{"j", "l", "j", "h"}, "sensors", 5},
+		{"page down", []string{"l", "pgdown"}, "spBv1.0", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(testTree())
+			m.SetHeight(6)
+			for _, k := range tt.keys {
+				m, _ = m.Update(keyMsg(k))
+			}
+			if m.selected != tt.selected {
+				t.Errorf("selected %q, want %q", m.selected, tt.selected)
+			}
+			if rows := m.tree.rows(m.expanded); len(rows) != tt.rows {
+				t.Errorf("%d rows visible, want %d", len(rows), tt.rows)
+			}
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string // selecting the row
+		want subscription.Data
+	}{
+		{"subtree", []string{"j"}, subscription.Data{Name: "sensors/#", Topic: "sensors/#", Format: "none"}},
+		{"topic with subtopics", []string{"j", "l", "j"}, subscription.Data{Name: "sensors/1/#", Topic: "sensors/1/#", Format: "none"}},
+		{"json topic", []string{"j", "l", "j", "j", "l", "j"}, subscription.Data{Name: "sensors/2/temp", Topic: "sensors/2/temp", Format: "json"}},
+		{"empty level", []string{"l", "j"}, subscription.Data{Name: "/leading", Topic: "/leading", Format: "none"}},
+		{"sparkplug", []string{"j", "j", "l", "j", "l", "j", "l", "j"}, subscription.Data{Name: "spBv1.0/g/NDATA/n", Topic: "spBv1.0/g/NDATA/n", Format: "sparkplug"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(testTree())
+			m.SetHeight(20)
+			for _, k := range tt.keys {
+				m, _ = m.Update(keyMsg(k))
+			}
+			_, cmd := m.Update(keyMsg("s"))
+			if cmd == nil {
+				t.Fatal("no command")
+			}
+			msg, ok := cmd().(SubscribeMsg)
+			if !ok || msg.Id != m.Id() {
+				t.Fatalf("got %#v, want a SubscribeMsg of the model", msg)
+			}
+			if msg.Data != tt.want {
+				t.Errorf("subscription %+v, want %+v", msg.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	m := New(NewTree())
+	m.SetHeight(12)
+	if view := m.View(lipgloss.NewStyle(), 60); !strings.Contains(view, "no messages received yet") {
+		t.Errorf("empty tree not shown as such:\n%s", view)
+	}
+
+	m = New(testTree())
+	m.SetHeight(12)
+	m, _ = m.Update(keyMsg("j"))
+	m, _ = m.Update(keyMsg("l"))
+	m, _ = m.Update(keyMsg("j"))
+	view := m.View(lipgloss.NewStyle(), 60)
+	for _, want := range []string{"▾ sensors (4)", "▸ 1 (3) R = on", "sensors/1 │ 1 messages"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view does not show %q:\n%s", want, view)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package topictree
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+)
+
+// Tree is the hierarchy of every topic seen on a connection, it is safe to
+// use from the client's message handler and the view at the same time
+type Tree struct {
+	mu   sync.Mutex
+	root *node
+}
+
+type node struct {
+	name     string
+	path     string
+	children map[string]*node
+
+	total    int // messages on this topic and every topic below it
+	count    int // messages on this exact topic
+	last     []byte
+	lastAt   time.Time
+	retained bool
+}
+
+// row is a visible line of the tree
+type row struct {
+	path        string
+	name        string
+	depth       int
+	hasChildren bool
+	expanded    bool
+	total       int
+	count       int
+	last        []byte
+	lastAt      time.Time
+	retained    bool
+}
+
+func NewTree() *Tree {
+	return &Tree{root: &node{children: make(map[string]*node)}}
+}
+
+// Add records msg received at at, which has to be the latest message
+func (t *Tree) Add(msg client.Message, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := t.root
+	n.total++
+	for i, level := range strings.Split(msg.Topic, "/") {
+		child, ok := n.children[level]
+		if !ok {
+			path := level
+			if i > 0 {
+				path = n.path + "/" + level
+			}
+			child = &node{name: level, path: path, children: make(map[string]*node)}
+			n.children[level] = child
+		}
+		n = child
+		n.total++
+	}
+	n.count++
+	n.last = msg.Payload
+	n.lastAt = at
+	n.retained = msg.Retained
+}
+
+// rows flattens the tree into the lines visible with the expanded paths
+func (t *Tree) rows(expanded map[string]bool) []row {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rows := make([]row, 0)
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			child := n.children[name]
+			r := row{
+				path:        child.path,
+				name:        child.name,
+				depth:       depth,
+				hasChildren: len(child.children) > 0,
+				expanded:    expanded[child.path],
+				total:       child.total,
+				count:       child.count,
+				last:        child.last,
+				lastAt:      child.lastAt,
+				retained:    child.retained,
+			}
+			rows = append(rows, r)
+			if r.expanded {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(t.root, 0)
+	return rows
+}
@@ -4,15 +4,18 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync/atomic"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type Model struct {
+	id     int64
 	title  string
 	inputs any
 	cursor int
@@ -29,8 +32,17 @@ type MultipleChoice struct {
 	index   int
 }
 
-type SubmitMsg struct{}
-type CancelMsg struct{}
+// SubmitMsg and CancelMsg carry the Id of the form they come from, the forms
+// of connections in the background get them too
+type SubmitMsg struct {
+	Id int64
+}
+
+type CancelMsg struct {
+	Id int64
+}
+
+var lastId atomic.Int64
 
 func New(title string, inputs any) Model {
 	if inputs != nil {
@@ -47,6 +59,7 @@ func New(title string, inputs any) Model {
 	}
 
 	return Model{
+		id:         lastId.Add(1),
 		title:      title,
 		inputs:     inputs,
 		help:       help.New(),
@@ -63,12 +76,16 @@ func NewMultipleChoice(choices []string) MultipleChoice {
 	return MultipleChoice{choices: choices}
 }
 
-func submit() tea.Msg {
-	return SubmitMsg{}
+func (m Model) Id() int64 {
+	return m.id
 }
 
-func cancel() tea.Msg {
-	return CancelMsg{}
+func (m Model) submit() tea.Msg {
+	return SubmitMsg{Id: m.id}
+}
+
+func (m Model) cancel() tea.Msg {
+	return CancelMsg{Id: m.id}
 }
 
 func (m Model) Init() tea.Cmd {
@@ -145,9 +162,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 						}
 					}
 				} else if m.cursor == nrInputs { // cancel
-					return m, cancel
+					return m, m.cancel
 				} else { // submit
-					return m, submit
+					return m, m.submit
 				}
 
 			case key.Matches(msg, m.keysNormal.Next):
@@ -199,6 +216,11 @@ func (m Model) View() string {
 		case reflect.Struct:
 			switch v := v.Interface().(type) {
 			case textinput.Model:
+				// without a width only the first character of the
+				// placeholder is shown
+				if v.Width == 0 && v.Value() == "" {
+					v.Width = lipgloss.Width(v.Placeholder)
+				}
 				input = v.View()
 			case MultipleChoice:
 				var b strings.Builder
@@ -0,0 +1,27 @@
+package form
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+func TestViewShowsPlaceholders(t *testing.T) {
+	inputs := &struct {
+		Path  textinput.Model
+		Fixed textinput.Model
+	}{}
+	m := New("Test", inputs)
+	inputs.Path.Placeholder = "session recorded with R"
+	inputs.Fixed.Placeholder = "cut to its width"
+	inputs.Fixed.Width = 6
+
+	view := m.View()
+	if !strings.Contains(view, "session recorded with R") {
+		t.Errorf("placeholder of an input without a width is cut:\n%s", view)
+	}
+	if strings.Contains(view, "cut to its width") {
+		t.Errorf("placeholder of an input with a width is not cut:\n%s", view)
+	}
+}
@@ -3,22 +3,24 @@ package main
 import "github.com/charmbracelet/bubbles/key"
 
 type keyMap struct {
-	Add    key.Binding
-	Remove key.Binding
-	Edit   key.Binding
-	Up     key.Binding
-	Down   key.Binding
-	Select key.Binding
-	Quit   key.Binding
+	Add        key.Binding
+	Import     key.Binding
+	Remove     key.Binding
+	Edit       key.Binding
+	Disconnect key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	Select     key.Binding
+	Quit       key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Add, k.Remove, k.Edit, k.Select, k.Quit}
+	return []key.Binding{k.Add, k.Import, k.Remove, k.Edit, k.Select, k.Disconnect, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Add, k.Remove, k.Edit},
+		{k.Add, k.Import, k.Remove, k.Edit, k.Disconnect},
 		{k.Up, k.Down, k.Select},
 		{k.Quit},
 	}
@@ -29,6 +31,10 @@ var keys = keyMap{
 		key.WithKeys("a"),
 		key.WithHelp("a", "add connection"),
 	),
+	Import: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "open capture file as connection"),
+	),
 	Remove: key.NewBinding(
 		key.WithKeys("r"),
 		key.WithHelp("r", "remove connection"),
@@ -37,6 +43,10 @@ var keys = keyMap{
 		key.WithKeys("e"),
 		key.WithHelp("e", "edit connection"),
 	),
+	Disconnect: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "disconnect"),
+	),
 	Up: key.NewBinding(
 		key.WithKeys("up", "k"),
 		key.WithHelp("↑/k", "move up"),
@@ -54,3 +64,34 @@ var keys = keyMap{
 		key.WithHelp("q/esc/^c", "quit"),
 	),
 }
+
+type tabKeyMap struct {
+	Prefix key.Binding
+	Next   key.Binding
+	Prev   key.Binding
+	GoTo   key.Binding
+	Close  key.Binding
+}
+
+// the next and previous tab bindings only apply after the prefix
+var tabKeys = tabKeyMap{
+	Prefix: key.NewBinding(
+		key.WithKeys("g"),
+	),
+	Next: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("gt", "next tab"),
+	),
+	Prev: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("gT", "previous tab"),
+	),
+	GoTo: key.NewBinding(
+		key.WithKeys("alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9"),
+		key.WithHelp("alt+1-9", "go to tab"),
+	),
+	Close: key.NewBinding(
+		key.WithKeys("ctrl+w"),
+		key.WithHelp("^w", "close tab"),
+	),
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/Broderick-Westrope/charmutils"
 	"github.com/OmegaRelay/mqtt-tui/connection"
+	"github.com/OmegaRelay/mqtt-tui/connection/client"
+	"github.com/OmegaRelay/mqtt-tui/connection/msglog"
 	"github.com/OmegaRelay/mqtt-tui/form"
 	"github.com/OmegaRelay/mqtt-tui/program"
 	"github.com/OmegaRelay/mqtt-tui/styles"
@@ -39,7 +41,9 @@ const kConnectionSaveFileName = "connections.json"
 
 type model struct {
 	connections    list.Model
-	connection     tea.Model
+	activeId       string // id of the connection being viewed, empty shows the connections list
+	tabs           []string
+	tabPrefix      *tea.KeyMsg // held back g, see updateTabs
 	newConnection  tea.Model
 	editConnection bool
 
@@ -59,14 +63,26 @@ type newConnectionInputs struct {
 	Port         textinput.Model
 	Username     textinput.Model
 	Password     textinput.Model
-	UseTls       bool
+	Protocol     form.MultipleChoice
+	Transport    form.MultipleChoice
+	WsPath       textinput.Model
+	WsHeaders    textinput.Model
 	Authenticate bool
 	KeyFile      textinput.Model
 	CertFile     textinput.Model
 	CaFile       textinput.Model
+	KeyPassword  textinput.Model
+	ServerName   textinput.Model
+	TlsMin       form.MultipleChoice
+	TlsMax       form.MultipleChoice
+	Alpn         textinput.Model
+	TreeFilter   textinput.Model
+	MessageLog   bool
+	LogSize      textinput.Model // bytes per message log file before it is rotated
 }
 
 type newConnectionModel struct {
+	id   string // of the edited connection, empty for a new one
 	form form.Model
 }
 
@@ -162,12 +178,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	if m.connection != nil {
-		m.connection, cmd = m.connection.Update(msg)
-		return m, cmd
+	cmds := make([]tea.Cmd, 0)
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		cmds = append(cmds, m.updateBackground(msg))
+	}
+
+	if m.activeId != "" {
+		idx := m.connectionIndex(m.activeId)
+		if msg, ok := msg.(tea.KeyMsg); ok && idx >= 0 {
+			for _, msg := range m.updateTabs(msg, m.connections.Items()[idx].(connection.Model)) {
+				if m.activeId == "" {
+					break
+				}
+				cmds = append(cmds, m.updateActive(msg))
+			}
+			return m, tea.Batch(cmds...)
+		}
+		cmds = append(cmds, m.updateActive(msg))
+		return m, tea.Batch(cmds...)
 	} else if m.newConnection != nil {
 		m.newConnection, cmd = m.newConnection.Update(msg)
-		return m, cmd
+		cmds = append(cmds, cmd)
+		return m, tea.Batch(cmds...)
 	} else {
 		m.connections.Update(msg)
 	}
@@ -176,37 +208,75 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Quit):
+			m.disconnectAll()
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Add):
 			m.newConnection = NewConnectionModel(nil)
 			return m, m.newConnection.Init()
+		case key.Matches(msg, m.keys.Import):
+			m.newConnection = NewCaptureModel(nil)
+			return m, m.newConnection.Init()
 		case key.Matches(msg, m.keys.Remove):
 			items := m.connections.Items()
 			if len(items) == 0 {
 				break
 			}
+			conn := items[m.connections.GlobalIndex()].(connection.Model)
+			conn.Disconnect()
+			m.closeTab(conn.Data().Id)
 			m.connections.RemoveItem(m.connections.GlobalIndex())
 			m.saveConnections()
 		case key.Matches(msg, m.keys.Edit):
 			items := m.connections.Items()
+			if len(items) == 0 {
+				break
+			}
 			conn := items[m.connections.GlobalIndex()].(connection.Model)
-			inputs := newConnectionInputs{}.Copy(conn)
-			m.newConnection = NewConnectionModel(&inputs)
 			m.editConnection = true
+			if conn.Data().Capture != "" {
+				m.newConnection = NewCaptureModel(&conn)
+				return m, m.newConnection.Init()
+			}
+			inputs := newConnectionInputs{}.Copy(conn)
+			newConnection := NewConnectionModel(&inputs)
+			newConnection.id = conn.Data().Id
+			m.newConnection = newConnection
 			return m, m.newConnection.Init()
+		case key.Matches(msg, m.keys.Disconnect):
+			items := m.connections.Items()
+			if len(items) == 0 {
+				break
+			}
+			conn := items[m.connections.GlobalIndex()].(connection.Model)
+			m.connections.SetItem(m.connections.GlobalIndex(), conn.Disconnect())
 		case key.Matches(msg, m.keys.Down):
 			m.connections.CursorDown()
 		case key.Matches(msg, m.keys.Up):
 			m.connections.CursorUp()
 		case key.Matches(msg, m.keys.Select):
-			m.connection = m.connections.Items()[m.connections.GlobalIndex()].(connection.Model)
-			cmd := m.connection.Init()
-			return m, cmd
+			items := m.connections.Items()
+			if len(items) == 0 {
+				break
+			}
+			conn := items[m.connections.GlobalIndex()].(connection.Model)
+			if conn.IsOpen() {
+				cmd = conn.Init()
+			} else {
+				conn, cmd = conn.Connect()
+			}
+			m.connections.SetItem(m.connections.GlobalIndex(), conn)
+			m.openTab(conn.Data().Id)
+			cmds = append(cmds, cmd)
+			return m, tea.Batch(cmds...)
 		}
 
 	case newConnectionMsg:
 		if m.editConnection {
+			old := m.connections.Items()[m.connections.GlobalIndex()].(connection.Model)
+			old.Disconnect()
+			m.closeTab(old.Data().Id)
 			m.connections.SetItem(m.connections.GlobalIndex(), connection.Model(msg))
+			m.editConnection = false
 		} else {
 			items := m.connections.Items()
 			items = append(items, connection.Model(msg))
@@ -215,13 +285,73 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.saveConnections()
 	}
 
-	return m, nil
+	return m, tea.Batch(cmds...)
+}
+
+// updateActive passes msg to the connection being viewed, the connection
+// returns nil when its view is left
+func (m *model) updateActive(msg tea.Msg) tea.Cmd {
+	idx := m.connectionIndex(m.activeId)
+	if idx < 0 {
+		m.activeId = ""
+		return nil
+	}
+
+	conn, cmd := m.connections.Items()[idx].(connection.Model).Update(msg)
+	if conn == nil {
+		if !m.connections.Items()[idx].(connection.Model).IsOpen() {
+			m.closeTab(m.activeId)
+		}
+		m.activeId = ""
+		return cmd
+	}
+	m.connections.SetItem(idx, conn.(connection.Model))
+	return cmd
+}
+
+// updateBackground passes msg to every open connection which is not being
+// viewed so they keep track of their state and messages
+func (m *model) updateBackground(msg tea.Msg) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+	for i, item := range m.connections.Items() {
+		conn, ok := item.(connection.Model)
+		if !ok || !conn.IsOpen() || conn.Data().Id == m.activeId {
+			continue
+		}
+		updated, cmd := conn.Update(msg)
+		if updated == nil {
+			continue
+		}
+		m.connections.SetItem(i, updated.(connection.Model))
+		cmds = append(cmds, cmd)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m model) connectionIndex(id string) int {
+	for i, item := range m.connections.Items() {
+		conn, ok := item.(connection.Model)
+		if ok && conn.Data().Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m model) disconnectAll() {
+	for _, item := range m.connections.Items() {
+		conn, ok := item.(connection.Model)
+		if ok {
+			conn.Disconnect()
+		}
+	}
 }
 
 func (m model) View() string {
 	s := ""
-	if m.connection != nil {
-		s = m.connection.View()
+	if idx := m.connectionIndex(m.activeId); idx >= 0 {
+		s = m.connections.Items()[idx].(connection.Model).View()
+		s = m.tabBarView(s)
 	} else {
 		borderStyle := styles.FocusedBorderStyle
 		if m.newConnection != nil {
@@ -276,7 +406,17 @@ func NewConnectionModel(inputs *newConnectionInputs) newConnectionModel {
 	m := newConnectionModel{}
 	if inputs == nil {
 		inputs = &newConnectionInputs{}
-		m.form = form.New("New Connection", &newConnectionInputs{})
+		inputs.Protocol = form.NewMultipleChoice(client.ProtocolVersionChoices())
+		inputs.Transport = form.NewMultipleChoice(connection.TransportChoices())
+		inputs.TlsMin = form.NewMultipleChoice(connection.TlsVersionChoices())
+		inputs.TlsMax = form.NewMultipleChoice(connection.TlsVersionChoices())
+		m.form = form.New("New Connection", inputs)
+		inputs.KeyPassword.EchoMode = textinput.EchoPassword
+		inputs.Alpn.Placeholder = "x-amzn-mqtt-ca"
+		inputs.WsPath.Placeholder = "/mqtt"
+		inputs.WsHeaders.Placeholder = "Key: Value; Key2: Value2"
+		inputs.TreeFilter.Placeholder = connection.DefaultTreeFilter
+		inputs.LogSize.Placeholder = strconv.Itoa(msglog.DefaultMaxSize)
 	} else {
 		m.form = form.New("New Connection", nil)
 		m.form.SetInputs(inputs)
@@ -289,11 +429,15 @@ func (m newConnectionModel) Init() tea.Cmd {
 }
 
 func (m newConnectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg.(type) {
+	switch msg := msg.(type) {
 	case form.SubmitMsg:
-		return nil, m.complete
+		if msg.Id == m.form.Id() {
+			return nil, m.complete
+		}
 	case form.CancelMsg:
-		return nil, nil
+		if msg.Id == m.form.Id() {
+			return nil, nil
+		}
 	}
 
 	var cmd tea.Cmd
@@ -303,8 +447,8 @@ func (m newConnectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m newConnectionModel) View() string {
 	content := m.form.View()
-	width, _, _ := term.GetSize(0)
-	widget := viewport.New(width-4, 20)
+	width, height, _ := term.GetSize(0)
+	widget := viewport.New(width-4, min(lipgloss.Height(content), height-4))
 	widget.SetContent(content)
 	return styles.FocusedBorderStyle.Render(widget.View())
 }
@@ -312,21 +456,47 @@ func (m newConnectionModel) View() string {
 func (m newConnectionModel) complete() tea.Msg {
 	inputs := m.form.Inputs().(*newConnectionInputs)
 	port, _ := strconv.ParseInt(inputs.Port.Value(), 10, 32)
+	headers, err := connection.ParseHeaders(inputs.WsHeaders.Value())
+	if err != nil {
+		return program.ErrorMsg{Err: err}
+	}
+	var logSize int64
+	if v := inputs.LogSize.Value(); v != "" {
+		logSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || logSize < 0 {
+			return program.ErrorMsg{Err: fmt.Errorf("invalid message log size %q", v)}
+		}
+	}
 
+	id := m.id
+	if id == "" {
+		id = uuid.NewString()
+	}
 	newModel := connection.NewModel(
 		connection.Data{
-			Name:         inputs.Name.Value(),
-			Broker:       inputs.Broker.Value(),
-			Port:         int(port),
-			ClientId:     inputs.ClientId.Value(),
-			Username:     inputs.Username.Value(),
-			Password:     inputs.Password.Value(),
-			UseTls:       inputs.UseTls,
-			Authenticate: inputs.Authenticate,
-			KeyFilePath:  inputs.KeyFile.Value(),
-			CertFilePath: inputs.CertFile.Value(),
-			CaFilePath:   inputs.CaFile.Value(),
-			Id:           uuid.NewString(),
+			Name:            inputs.Name.Value(),
+			Broker:          inputs.Broker.Value(),
+			Port:            int(port),
+			ClientId:        inputs.ClientId.Value(),
+			Username:        inputs.Username.Value(),
+			Password:        inputs.Password.Value(),
+			ProtocolVersion: inputs.Protocol.Selected(),
+			Transport:       inputs.Transport.Selected(),
+			WsPath:          inputs.WsPath.Value(),
+			WsHeaders:       headers,
+			Authenticate:    inputs.Authenticate,
+			KeyFilePath:     inputs.KeyFile.Value(),
+			CertFilePath:    inputs.CertFile.Value(),
+			CaFilePath:      inputs.CaFile.Value(),
+			KeyPassword:     inputs.KeyPassword.Value(),
+			ServerName:      inputs.ServerName.Value(),
+			TlsMinVersion:   inputs.TlsMin.Selected(),
+			TlsMaxVersion:   inputs.TlsMax.Selected(),
+			Alpn:            inputs.Alpn.Value(),
+			TreeFilter:      inputs.TreeFilter.Value(),
+			MessageLog:      inputs.MessageLog,
+			MessageLogSize:  logSize,
+			Id:              id,
 		},
 	)
 
@@ -350,11 +520,46 @@ func (m newConnectionInputs) Copy(conn connection.Model) newConnectionInputs {
 	m.Port.SetValue(strconv.FormatInt(int64(data.Port), 10))
 	m.Username.SetValue(data.Username)
 	m.Password.SetValue(data.Password)
-	m.UseTls = data.UseTls
+	m.Protocol = form.NewMultipleChoice(client.ProtocolVersionChoices())
+	for i, v := range client.ProtocolVersionChoices() {
+		if data.ProtocolVersion != v {
+			continue
+		}
+		m.Protocol.SetIndex(i)
+	}
+	m.Transport = form.NewMultipleChoice(connection.TransportChoices())
+	for i, v := range connection.TransportChoices() {
+		if data.TransportOrDefault() != v {
+			continue
+		}
+		m.Transport.SetIndex(i)
+	}
+	m.WsPath.SetValue(data.WsPath)
+	m.WsHeaders.SetValue(connection.FormatHeaders(data.WsHeaders))
 	m.Authenticate = data.Authenticate
 	m.KeyFile.SetValue(data.KeyFilePath)
 	m.CertFile.SetValue(data.CertFilePath)
 	m.CaFile.SetValue(data.CaFilePath)
+	m.KeyPassword.SetValue(data.KeyPassword)
+	m.KeyPassword.EchoMode = textinput.EchoPassword
+	m.ServerName.SetValue(data.ServerName)
+	m.TlsMin = form.NewMultipleChoice(connection.TlsVersionChoices())
+	m.TlsMax = form.NewMultipleChoice(connection.TlsVersionChoices())
+	for i, v := range connection.TlsVersionChoices() {
+		if data.TlsMinVersion == v {
+			m.TlsMin.SetIndex(i)
+		}
+		if data.TlsMaxVersion == v {
+			m.TlsMax.SetIndex(i)
+		}
+	}
+	m.Alpn.SetValue(data.Alpn)
+	m.TreeFilter.SetValue(data.TreeFilter)
+	m.MessageLog = data.MessageLog
+	m.LogSize.Placeholder = strconv.Itoa(msglog.DefaultMaxSize)
+	if data.MessageLogSize > 0 {
+		m.LogSize.SetValue(strconv.FormatInt(data.MessageLogSize, 10))
+	}
 
 	return m
 }
@@ -1,6 +1,11 @@
 package styles
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 const MenuWidth = 40
 
@@ -16,4 +21,64 @@ var (
 	BlurredBorderStyle = lipgloss.NewStyle().
 				Border(lipgloss.RoundedBorder()).
 				BorderForeground(lipgloss.Color("238"))
+
+	ConnectedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	ConnectingStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	DisconnectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	IdleStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+
+	ActiveTabStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("231")).Background(lipgloss.Color("38"))
+	InactiveTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// JSON syntax highlighting
+var (
+	JsonKeyStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("38"))
+	JsonStringStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("114"))
+	JsonNumberStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("179"))
+	JsonBoolStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	JsonNullStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	JsonPunctuationStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// MatchStyle highlights search matches
+var MatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("220"))
+
+// Highlight renders the parts of s matched by re with MatchStyle and the rest
+// with base, re may be nil. Lines are highlighted one by one as rendering
+// multiple lines pads them to the same width.
+func Highlight(s string, re *regexp.Regexp, base lipgloss.Style) string {
+	if strings.Contains(s, "\n") {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = Highlight(line, re, base)
+		}
+		return strings.Join(lines, "\n")
+	}
+	if re == nil {
+		return base.Render(s)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if loc[0] > last {
+			b.WriteString(base.Render(s[last:loc[0]]))
+		}
+		b.WriteString(MatchStyle.Render(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	if last < len(s) {
+		b.WriteString(base.Render(s[last:]))
+	}
+	return b.String()
+}
+
+// diff colours
+var (
+	DiffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	DiffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	DiffChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
 )
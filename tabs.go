@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Broderick-Westrope/charmutils"
+	"github.com/OmegaRelay/mqtt-tui/connection"
+	"github.com/OmegaRelay/mqtt-tui/styles"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openTab shows the connection with id, adding a tab for it if it does not
+// have one yet
+func (m *model) openTab(id string) {
+	if !slices.Contains(m.tabs, id) {
+		m.tabs = append(m.tabs, id)
+	}
+	m.activeId = id
+}
+
+// closeTab removes the tab of the connection with id and shows its neighbour,
+// or the connections list if it was the last tab. The connection itself stays
+// connected.
+func (m *model) closeTab(id string) {
+	idx := slices.Index(m.tabs, id)
+	if idx < 0 {
+		return
+	}
+	m.tabs = slices.Delete(m.tabs, idx, idx+1)
+	if m.activeId != id {
+		return
+	}
+	if len(m.tabs) == 0 {
+		m.activeId = ""
+		return
+	}
+	m.activeId = m.tabs[min(idx, len(m.tabs)-1)]
+}
+
+// updateTabs handles the tab key bindings while a connection is shown and
+// returns the key presses to pass on to the connection. A g is held back
+// until the next key shows whether it starts gt or gT.
+func (m *model) updateTabs(msg tea.KeyMsg, conn connection.Model) []tea.KeyMsg {
+	if m.tabPrefix != nil {
+		prefix := *m.tabPrefix
+		m.tabPrefix = nil
+		switch {
+		case key.Matches(msg, tabKeys.Next):
+			m.switchTab(1)
+			return nil
+		case key.Matches(msg, tabKeys.Prev):
+			m.switchTab(-1)
+			return nil
+		}
+		return append([]tea.KeyMsg{prefix}, m.updateTabs(msg, conn)...)
+	}
+
+	switch {
+	case conn.HasDialog():
+		return []tea.KeyMsg{msg}
+	case key.Matches(msg, tabKeys.GoTo):
+		idx := int(msg.Runes[0] - '1')
+		if idx < len(m.tabs) {
+			m.activeId = m.tabs[idx]
+		}
+		return nil
+	case key.Matches(msg, tabKeys.Prefix):
+		m.tabPrefix = &msg
+		return nil
+	case key.Matches(msg, tabKeys.Close):
+		m.closeTab(m.activeId)
+		return nil
+	}
+	return []tea.KeyMsg{msg}
+}
+
+func (m *model) switchTab(offset int) {
+	idx := slices.Index(m.tabs, m.activeId)
+	if idx < 0 || len(m.tabs) == 0 {
+		return
+	}
+	idx = (idx + offset + len(m.tabs)) % len(m.tabs)
+	m.activeId = m.tabs[idx]
+}
+
+// tabBarView draws the tabs over the top border of the connection view
+func (m model) tabBarView(s string) string {
+	width := lipgloss.Width(s) - 4
+	if width <= 0 {
+		return s
+	}
+
+	tabs := make([]string, 0, len(m.tabs))
+	for i, id := range m.tabs {
+		idx := m.connectionIndex(id)
+		if idx < 0 {
+			continue
+		}
+		conn := m.connections.Items()[idx].(connection.Model)
+		style := styles.InactiveTabStyle
+		if id == m.activeId {
+			style = styles.ActiveTabStyle
+		}
+		label := style.Render(fmt.Sprintf(" %d ", i+1)) + conn.StatusIcon() + style.Render(fmt.Sprintf(" %s ", conn.Data().Name))
+		tabs = append(tabs, label)
+	}
+	bar := strings.Join(tabs, styles.InactiveTabStyle.Render("│"))
+	hints := []string{}
+	for _, k := range []key.Binding{tabKeys.Next, tabKeys.Prev, tabKeys.GoTo, tabKeys.Close} {
+		hints = append(hints, fmt.Sprintf("%s %s", k.Help().Key, k.Help().Desc))
+	}
+	bar += styles.InactiveTabStyle.Render(" ─ " + strings.Join(hints, " • ") + " ")
+	if lipgloss.Width(bar) > width {
+		bar = lipgloss.NewStyle().MaxWidth(width).Render(bar)
+	}
+
+	s, _ = charmutils.Overlay(s, bar, 0, 2, false)
+	return s
+}